@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/atexit"
+	"github.com/richardwilkes/toolbox/errs"
+	"github.com/richardwilkes/toolbox/xio"
+	rawterm "golang.org/x/term"
+)
+
+const (
+	// askpassSocketEnv names the environment variable that tells an instance of gp run by git or ssh to ask for a
+	// credential, rather than behaving normally, and where to send the request.
+	askpassSocketEnv = "GP_ASKPASS_SOCKET"
+	// askpassRepoEnv names the environment variable that identifies the repo on whose behalf a credential is asked for.
+	askpassRepoEnv = "GP_ASKPASS_REPO"
+)
+
+// prompts serializes the credential prompts from the many git processes that may be running at once, and caches the
+// answers so that the same question is only asked once, unless the answer turns out to be wrong.
+var prompts struct {
+	lock    sync.Mutex
+	answers map[string]string
+	env     []string       // the environment that routes git's and ssh's prompts to gp, once set up
+	redraw  chan os.Signal // when the display is being updated in place, signaled to redraw it after a prompt
+	active  bool           // true while the display is being updated in place
+
+	// served holds the answers given for each repo, by prompt
+	served map[string]map[string]askpassUse
+}
+
+// askpassUse records an answer given for a repo and the process that asked for it.
+type askpassUse struct {
+	answer string
+	asker  int
+}
+
+type askpassRequest struct {
+	Prompt string `json:"prompt"`
+	Repo   string `json:"repo,omitempty"`
+	Asker  int    `json:"asker,omitempty"` // the process id of git or ssh, which ran gp to ask
+}
+
+type askpassResponse struct {
+	Answer string `json:"answer"`
+	OK     bool   `json:"ok"`
+}
+
+// startAskpass arranges for git and ssh to send their prompts for credentials to gp, rather than each writing to the
+// terminal at the same time, where the display would overwrite them. This is only done when stdin is a terminal, since
+// otherwise there is no one to answer them.
+func startAskpass() error {
+	if !rawterm.IsTerminal(int(os.Stdin.Fd())) {
+		return nil
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	socket := filepath.Join(os.TempDir(), fmt.Sprintf("gp-askpass-%d.sock", os.Getpid()))
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return errs.NewWithCause("unable to listen on "+socket, err)
+	}
+	atexit.Register(func() {
+		xio.CloseIgnoringErrors(listener)
+		if removeErr := os.Remove(socket); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+			fmt.Fprintln(os.Stderr,
+				multirepo.ErrorText(errs.NewWithCause("unable to remove socket "+socket, removeErr)))
+		}
+	})
+	prompts.answers = make(map[string]string)
+	prompts.served = make(map[string]map[string]askpassUse)
+	prompts.env = []string{
+		askpassSocketEnv + "=" + socket,
+		"GIT_ASKPASS=" + exe,
+		"SSH_ASKPASS=" + exe,
+		"SSH_ASKPASS_REQUIRE=force",
+	}
+	go func() {
+		for {
+			conn, acceptErr := listener.Accept()
+			if acceptErr != nil {
+				return
+			}
+			go handleAskpass(conn)
+		}
+	}()
+	return nil
+}
+
+func handleAskpass(conn net.Conn) {
+	defer xio.CloseIgnoringErrors(conn)
+	var req askpassRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	var rsp askpassResponse
+	rsp.Answer, rsp.OK = ask(&req)
+	if err := json.NewEncoder(conn).Encode(&rsp); err != nil {
+		fmt.Fprintln(os.Stderr, multirepo.ErrorText(errs.Wrap(err)))
+	}
+}
+
+// ask presents the request's prompt to the user and returns their answer, or the answer given previously for the same
+// prompt. Should the process that was given an answer ask again, as ssh does when a passphrase is wrong, the answer is
+// dropped and the user asked anew. The display is paused while waiting. Returns false if the prompt can't be answered,
+// as when the full-screen TUI, which owns the terminal's input, is in use.
+func ask(req *askpassRequest) (string, bool) {
+	prompts.lock.Lock()
+	defer prompts.lock.Unlock()
+	prompt := req.Prompt
+	if answer, ok := prompts.answers[prompt]; ok {
+		if use, served := prompts.served[req.Repo][prompt]; !served || use.asker != req.Asker || req.Asker == 0 {
+			recordAnswer(req, answer)
+			return answer, true
+		}
+		delete(prompts.answers, prompt)
+	}
+	if tui {
+		return "", false
+	}
+	if prompts.active {
+		t := newANSI(os.Stdout)
+		t.Reset()
+		t.Clear()
+		t.Position(1, 1)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	var answer string
+	var err error
+	if isSecret(prompt) {
+		var data []byte
+		data, err = rawterm.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		answer = string(data)
+	} else {
+		answer, err = bufio.NewReader(os.Stdin).ReadString('\n')
+		answer = strings.TrimRight(answer, "\r\n")
+	}
+	if prompts.active {
+		select {
+		case prompts.redraw <- os.Interrupt:
+		default:
+		}
+	}
+	if err != nil {
+		return "", false
+	}
+	prompts.answers[prompt] = answer
+	recordAnswer(req, answer)
+	return answer, true
+}
+
+// recordAnswer notes the answer given for the request's repo, so that it can be dropped should it be refused. The
+// prompts lock must be held.
+func recordAnswer(req *askpassRequest, answer string) {
+	if req.Repo == "" {
+		return
+	}
+	uses, ok := prompts.served[req.Repo]
+	if !ok {
+		uses = make(map[string]askpassUse)
+		prompts.served[req.Repo] = uses
+	}
+	uses[req.Prompt] = askpassUse{answer: answer, asker: req.Asker}
+}
+
+// forgetRefusedAnswers drops the cached answers given for the repo should err, from a git command, with output as its
+// output, show that its credentials were refused, so that a mistyped password or token isn't given for every other repo
+// as well.
+func (r *repo) forgetRefusedAnswers(out string, err error) {
+	if err == nil || prompts.served == nil {
+		return
+	}
+	var ce *multirepo.ClassifiedError
+	if !errors.As(multirepo.Classify(err, out), &ce) || ce.Kind != multirepo.AuthenticationDenied {
+		return
+	}
+	prompts.lock.Lock()
+	defer prompts.lock.Unlock()
+	for prompt, use := range prompts.served[r.path] {
+		// The user may already have given a different answer for another repo
+		if answer, ok := prompts.answers[prompt]; ok && answer == use.answer {
+			delete(prompts.answers, prompt)
+		}
+	}
+	delete(prompts.served, r.path)
+}
+
+// isSecret returns true if the answer to prompt shouldn't be echoed.
+func isSecret(prompt string) bool {
+	lower := strings.ToLower(prompt)
+	return strings.Contains(lower, "password") || strings.Contains(lower, "passphrase") ||
+		strings.Contains(lower, " pin")
+}
+
+// runAskpass is used in place of the normal behavior when gp has been run by git or ssh to ask for a credential. The
+// prompt is forwarded to the instance of gp that ran them, and the answer printed for git or ssh to read.
+func runAskpass(socket, prompt string) int {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		fmt.Fprintln(os.Stderr,
+			multirepo.ErrorText(errs.NewWithCause("unable to reach gp to ask for credentials", err)))
+		return 1
+	}
+	defer xio.CloseIgnoringErrors(conn)
+	req := askpassRequest{Prompt: prompt, Repo: os.Getenv(askpassRepoEnv), Asker: os.Getppid()}
+	if err = json.NewEncoder(conn).Encode(&req); err != nil {
+		fmt.Fprintln(os.Stderr, multirepo.ErrorText(errs.Wrap(err)))
+		return 1
+	}
+	var rsp askpassResponse
+	if err = json.NewDecoder(conn).Decode(&rsp); err != nil || !rsp.OK {
+		return 1
+	}
+	fmt.Println(rsp.Answer)
+	return 0
+}
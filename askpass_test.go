@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/richardwilkes/toolbox/check"
+)
+
+func TestAskpassCache(t *testing.T) {
+	savedTUI := tui
+	defer func() {
+		tui = savedTUI
+		prompts.answers = nil
+		prompts.served = nil
+	}()
+	// With the TUI in use, only cached answers can be given, so nothing is read from the terminal
+	tui = true
+	prompts.answers = map[string]string{"Passphrase:": "wrong"}
+	prompts.served = make(map[string]map[string]askpassUse)
+
+	answer, ok := ask(&askpassRequest{Prompt: "Passphrase:", Repo: "/src/a", Asker: 100})
+	check.True(t, ok)
+	check.Equal(t, "wrong", answer)
+	answer, ok = ask(&askpassRequest{Prompt: "Passphrase:", Repo: "/src/b", Asker: 200})
+	check.True(t, ok)
+	check.Equal(t, "wrong", answer)
+
+	// The same process asking again means the answer it was given was refused
+	_, ok = ask(&askpassRequest{Prompt: "Passphrase:", Repo: "/src/a", Asker: 100})
+	check.False(t, ok)
+	_, ok = prompts.answers["Passphrase:"]
+	check.False(t, ok)
+
+	// A failure other than authentication leaves the answers alone
+	prompts.answers["Passphrase:"] = "right"
+	prompts.served["/src/c"] = map[string]askpassUse{"Passphrase:": {answer: "right", asker: 300}}
+	r := &repo{path: "/src/c"}
+	failed := errors.New("exit status 128")
+	r.forgetRefusedAnswers("fatal: couldn't find remote ref main", failed)
+	check.Equal(t, "right", prompts.answers["Passphrase:"])
+	r.forgetRefusedAnswers("", nil)
+	check.Equal(t, "right", prompts.answers["Passphrase:"])
+
+	// An answer given since the one the repo was refused with is kept
+	(&repo{path: "/src/b"}).forgetRefusedAnswers("git@example.com: Permission denied (publickey).", failed)
+	check.Equal(t, "right", prompts.answers["Passphrase:"])
+
+	r.forgetRefusedAnswers("git@example.com: Permission denied (publickey).", failed)
+	_, ok = prompts.answers["Passphrase:"]
+	check.False(t, ok)
+	check.Equal(t, 1, len(prompts.served), "only /src/a should remain")
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/cmdline"
+	"github.com/richardwilkes/toolbox/xio/term"
+)
+
+// auditReason is the reason recorded for the repos the audit command finds not to comply with the policy.
+const auditReason = "noncompliant"
+
+// auditPolicy holds the configuration that the audit command checks the repos against.
+type auditPolicy struct {
+	// Remotes holds globs, as for --remote-match, one of which the URL of each of a repo's remotes must match
+	Remotes []string `yaml:"remotes,omitempty"`
+	// Email is a glob that the user.email in effect for a repo must match, such as "*@example.com"
+	Email string `yaml:"email,omitempty"`
+	// Signing requires commits to be configured to be signed
+	Signing bool `yaml:"signing,omitempty"`
+}
+
+type auditCmd struct{}
+
+func (c *auditCmd) Name() string {
+	return "audit"
+}
+
+func (c *auditCmd) Usage() string {
+	return "Reports the origin URL, the user.email in effect, and whether commits are configured to be signed for " +
+		"each repo, flagging those that don't comply with the audit policy in the configuration file: remotes on " +
+		"unexpected hosts, an unexpected email, or unsigned commits."
+}
+
+func (c *auditCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	locking = false
+	recording = false
+	addCommonOptions(cl)
+	run(cl.Parse(args), auditRepo)
+	return nil
+}
+
+func auditRepo(r *repo) {
+	// git exits with a failure status when none of the keys are set, which leaves nothing to report an error with
+	out, err := r.git("config", "--get-regexp", `^(remote\..*\.url|user\.email|commit\.gpgsign)$`)
+	if err != nil && out != "" {
+		r.fail("error", err)
+		return
+	}
+	var signing bool
+	for _, line := range strings.Split(out, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch {
+		case key == "user.email":
+			r.result.Email = value
+		case key == "commit.gpgsign":
+			signing = configBool(value)
+		case strings.HasPrefix(key, "remote.") && strings.HasSuffix(key, ".url"):
+			if r.result.Remotes == nil {
+				r.result.Remotes = make(map[string]string)
+			}
+			r.result.Remotes[strings.TrimSuffix(strings.TrimPrefix(key, "remote."), ".url")] = value
+		default:
+		}
+	}
+	r.result.Signed = signing
+	policy := &r.cfg.Audit
+	var problems []string
+	names := make([]string, 0, len(r.result.Remotes))
+	for name := range r.result.Remotes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if len(policy.Remotes) != 0 {
+		for _, name := range names {
+			remoteURL := r.result.Remotes[name]
+			matches := func(pattern string) bool { return remoteMatches(pattern, remoteURL) }
+			if !slices.ContainsFunc(policy.Remotes, matches) {
+				problems = append(problems, fmt.Sprintf("remote %s at %s", name, normalizeRemoteURL(remoteURL)))
+			}
+		}
+	}
+	if policy.Email != "" {
+		if matched, _ := path.Match(policy.Email, r.result.Email); !matched {
+			if r.result.Email == "" {
+				problems = append(problems, "no user.email")
+			} else {
+				problems = append(problems, "user.email "+r.result.Email)
+			}
+		}
+	}
+	if policy.Signing && !signing {
+		problems = append(problems, "commits not signed")
+	}
+	parts := make([]string, 0, 3)
+	if origin, ok := r.result.Remotes["origin"]; ok {
+		parts = append(parts, normalizeRemoteURL(origin))
+	} else {
+		parts = append(parts, "no origin")
+	}
+	if r.result.Email != "" {
+		parts = append(parts, r.result.Email)
+	} else {
+		parts = append(parts, "no email")
+	}
+	if signing {
+		parts = append(parts, "signed")
+	} else {
+		parts = append(parts, "unsigned")
+	}
+	status := strings.Join(parts, ", ")
+	if len(problems) == 0 {
+		r.succeeded(status)
+		return
+	}
+	r.result.Reason = auditReason
+	r.result.Warnings = problems
+	r.finish(multirepo.Failed, status, failedColor, term.Bold)
+}
+
+// configBool returns the value of a boolean git configuration setting, as git interprets it.
+func configBool(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "yes", "on", "1", "":
+		// A key without a value is true
+		return true
+	default:
+		return false
+	}
+}
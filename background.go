@@ -0,0 +1,54 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/richardwilkes/toolbox/xio"
+	rawterm "golang.org/x/term"
+)
+
+// queryBackground asks the terminal for its background color using an OSC 11 query. A primary device attributes query
+// follows it, which all terminals answer, so that the lack of support for the first can be detected without waiting
+// for the full timeout.
+func queryBackground() (dark, ok bool) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return false, false
+	}
+	defer xio.CloseIgnoringErrors(tty)
+	// Some platforms can't poll terminals, in which case a read might never return
+	if err = tty.SetReadDeadline(time.Now().Add(250 * time.Millisecond)); err != nil {
+		return false, false
+	}
+	conn, err := tty.SyscallConn()
+	if err != nil {
+		return false, false
+	}
+	var oldState *rawterm.State
+	var rawErr error
+	err = conn.Control(func(fd uintptr) { oldState, rawErr = rawterm.MakeRaw(int(fd)) })
+	if err != nil || rawErr != nil {
+		return false, false
+	}
+	defer conn.Control(func(fd uintptr) { rawterm.Restore(int(fd), oldState) })
+	if _, err = tty.WriteString("\x1b]11;?\x1b\\\x1b[c"); err != nil {
+		return false, false
+	}
+	var reply []byte
+	buf := make([]byte, 64)
+	for {
+		n, readErr := tty.Read(buf)
+		reply = append(reply, buf[:n]...)
+		if readErr != nil {
+			return false, false
+		}
+		// The device attributes reply has the form ESC [ ? ... c
+		if i := strings.Index(string(reply), "\x1b[?"); i != -1 && strings.Contains(string(reply[i:]), "c") {
+			return oscColorIsDark(string(reply[:i]))
+		}
+	}
+}
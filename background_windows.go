@@ -0,0 +1,6 @@
+package main
+
+// queryBackground is a no-op on Windows, where the console can't be read with a timeout.
+func queryBackground() (dark, ok bool) {
+	return false, false
+}
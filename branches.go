@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+// defaultBranch returns the remote-tracking ref of origin's default branch, such as "origin/main". This comes from
+// origin/HEAD when it has been set, with origin/main and origin/master tried otherwise.
+func (r *repo) defaultBranch() (string, error) {
+	if out, err := r.gitActual("symbolic-ref", "-q", "--short", "refs/remotes/origin/HEAD"); err == nil && out != "" {
+		return out, nil
+	}
+	for _, candidate := range []string{"origin/main", "origin/master"} {
+		if _, err := r.gitActual("rev-parse", "-q", "--verify", "refs/remotes/"+candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", errs.New("unable to determine the default branch")
+}
+
+// localBranches returns the local branches, excluding the current one, the local counterpart of the default branch, and
+// any checked out in other worktrees, that have been fully merged into the ref named by into. If gone is true, only
+// those whose upstream no longer exists are returned.
+func (r *repo) localBranches(into string, gone bool) ([]string, error) {
+	out, err := r.git("for-each-ref", "--merged="+into, "--format=%(refname:short)\t%(upstream:track)\t%(worktreepath)",
+		"refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	_, defBranch, _ := strings.Cut(into, "/")
+	var list []string
+	for _, line := range strings.Split(out, "\n") {
+		// Trailing empty fields may have been trimmed from the final line
+		fields := append(strings.Split(line, "\t"), "", "")
+		name, track, worktree := fields[0], fields[1], fields[2]
+		if name == "" || name == r.result.Branch || name == defBranch || worktree != "" || (gone && track != "[gone]") {
+			continue
+		}
+		list = append(list, name)
+	}
+	return list, nil
+}
+
+// deleteBranches deletes the local branches. Returns the number that were deleted.
+func (r *repo) deleteBranches(branches []string) (int, error) {
+	if len(branches) == 0 {
+		return 0, nil
+	}
+	// Forced, since the branches have already been confirmed to be merged into the default branch, which need not be
+	// the one git checks against.
+	if _, err := r.git(append([]string{"branch", "-D"}, branches...)...); err != nil {
+		return 0, err
+	}
+	return len(branches), nil
+}
+
+// switchTo checks out the local branch, creating it to track the remote branch of the same name if there is no local
+// one yet. Returns false, without an error, if neither exists.
+func (r *repo) switchTo(branch string) (bool, error) {
+	if _, err := r.gitActual("rev-parse", "-q", "--verify", "refs/heads/"+branch); err != nil {
+		if _, err = r.gitActual("rev-parse", "-q", "--verify", "refs/remotes/origin/"+branch); err != nil {
+			return false, nil
+		}
+		if _, err = r.git("switch", "--track", "origin/"+branch); err != nil {
+			return false, err
+		}
+	} else if _, err = r.git("switch", branch); err != nil {
+		return false, err
+	}
+	r.result.Branch = branch
+	r.drawBranch()
+	return true, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/cmdline"
+)
+
+type bundleCmd struct {
+	dest  string
+	stamp string
+}
+
+func (c *bundleCmd) Name() string {
+	return "bundle"
+}
+
+func (c *bundleCmd) Usage() string {
+	return "Creates a bundle containing all of the refs of every repo within a destination directory, " +
+		"as an offline backup."
+}
+
+func (c *bundleCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	locking = false
+	recording = false
+	addCommonOptions(cl)
+	cl.UsageSuffix = "<dest-dir> " + pathsUsage
+	paths := cl.Parse(args)
+	if len(paths) == 0 {
+		cl.FatalMsg("A destination directory must be specified")
+	}
+	var err error
+	if c.dest, err = filepath.Abs(paths[0]); err != nil {
+		cl.FatalMsg(multirepo.ErrorText(err))
+	}
+	if err = os.MkdirAll(c.dest, 0o755); err != nil {
+		cl.FatalMsg(multirepo.ErrorText(err))
+	}
+	c.stamp = time.Now().Format("20060102-150405")
+	run(paths[1:], c.bundleRepo)
+	return nil
+}
+
+func (c *bundleCmd) bundleRepo(r *repo) {
+	if !r.showBranch() {
+		return
+	}
+	if _, err := r.gitActual("rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		r.skip("no commits")
+		return
+	}
+	name := strings.ReplaceAll(filepath.ToSlash(r.name), "/", "_")
+	if name == "." || name == "" {
+		name = filepath.Base(r.path)
+	}
+	file := filepath.Join(c.dest, name+"-"+c.stamp+".bundle")
+	if _, err := r.git("bundle", "create", "--quiet", file, "--all"); err != nil {
+		r.fail("bundle failed", err)
+		return
+	}
+	fi, err := os.Stat(file)
+	if err != nil {
+		r.fail("error", err)
+		return
+	}
+	r.changed("bundled " + formatSize(fi.Size()) + " into " + filepath.Base(file))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/richardwilkes/toolbox/cmdline"
+)
+
+type cleanupCmd struct{}
+
+func (c *cleanupCmd) Name() string {
+	return "cleanup"
+}
+
+func (c *cleanupCmd) Usage() string {
+	return "Deletes the local branches that have been fully merged into the default branch, other than the current one."
+}
+
+func (c *cleanupCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	addCommonOptions(cl)
+	addDryRunOption(cl)
+	run(cl.Parse(args), cleanupRepo)
+	return nil
+}
+
+func cleanupRepo(r *repo) {
+	if !r.showBranch() {
+		return
+	}
+	def, err := r.defaultBranch()
+	if err != nil {
+		r.skipState("no default branch")
+		return
+	}
+	var list []string
+	if list, err = r.localBranches(def, false); err != nil {
+		r.fail("error", err)
+		return
+	}
+	switch {
+	case len(list) == 0:
+		r.succeeded("no merged branches")
+	case dryRun:
+		r.notice(fmt.Sprintf("would delete %d merged %s: %s", len(list), plural(len(list), "branch", "branches"),
+			strings.Join(list, ", ")))
+	default:
+		var count int
+		if count, err = r.deleteBranches(list); err != nil {
+			r.fail("failed to delete merged branches", err)
+			return
+		}
+		r.changed(fmt.Sprintf("deleted %d merged %s", count, plural(count, "branch", "branches")))
+	}
+}
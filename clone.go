@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/cmdline"
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+var (
+	cloneMissing bool
+	dryRun       bool
+)
+
+type cloneCmd struct{}
+
+func (c *cloneCmd) Name() string {
+	return "clone"
+}
+
+func (c *cloneCmd) Usage() string {
+	return "Clones the repos listed in a manifest that aren't present locally."
+}
+
+func (c *cloneCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	addCommonOptions(cl)
+	addDryRunOption(cl)
+	cl.UsageSuffix = ""
+	cl.Parse(args)
+	if manifest == "" {
+		cl.FatalMsg("--manifest must be specified")
+	}
+	cloneMissing = true
+	run(nil, func(r *repo) {
+		if r.showBranch() {
+			r.succeeded("already present")
+		}
+	})
+	return nil
+}
+
+// addCloneMissingOption adds the option to clone repos listed in a manifest that are missing.
+func addCloneMissingOption(cl *cmdline.CmdLine) {
+	cl.NewGeneralOption(&cloneMissing).SetName("clone-missing").
+		SetUsage("Clone any repos listed in the manifest that aren't present locally")
+}
+
+// addDryRunOption adds the option to report what would be done without doing it.
+func addDryRunOption(cl *cmdline.CmdLine) {
+	cl.NewGeneralOption(&dryRun).SetSingle('n').SetName("dry-run").
+		SetUsage("Show what would be cloned and pulled, and what would be skipped and why, without doing it")
+}
+
+func cloneRepo(r *repo) {
+	if r.url == "" {
+		r.skip("missing checkout with no URL in the manifest")
+		return
+	}
+	if dryRun {
+		r.notice("would clone from " + r.url)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		r.fail("failed to clone", errs.Wrap(err))
+		return
+	}
+	if _, err := r.git("clone", r.url, r.path); err != nil {
+		r.fail("failed to clone", err)
+		return
+	}
+	r.missing = false
+	if r.showBranch() {
+		r.changed("cloned")
+		if submodules {
+			r.updateSubmodules()
+		}
+		if lfs && r.result.Outcome != multirepo.Failed {
+			r.pullLFS()
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/richardwilkes/toolbox/errs"
+	"github.com/richardwilkes/toolbox/xio/term"
+	rawterm "golang.org/x/term"
+)
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+var colorMode = colorAuto
+
+// dim is a style, in addition to those provided by term, that displays the text faded.
+const dim = term.Blink << 1
+
+// ansi writes ANSI escape sequences to its output. Unlike term.ANSI, which omits them unless the output is a terminal,
+// it always does so, leaving the decision of whether they should be used to the caller.
+type ansi struct {
+	io.Writer
+}
+
+func newANSI(out io.Writer) *ansi {
+	return &ansi{Writer: out}
+}
+
+// Reset colors and styles.
+func (a *ansi) Reset() {
+	fmt.Fprint(a, "\033[m")
+}
+
+// Clear the screen.
+func (a *ansi) Clear() {
+	fmt.Fprint(a, "\033[2J")
+}
+
+// Position the cursor at the 1-based row and column.
+func (a *ansi) Position(row, column int) {
+	fmt.Fprintf(a, "\033[%d;%dH", row, column)
+}
+
+// EraseLineToEnd clears from the cursor position to the end of the current row.
+func (a *ansi) EraseLineToEnd() {
+	fmt.Fprint(a, "\033[K")
+}
+
+// Foreground sets the foreground color and style for subsequent output.
+func (a *ansi) Foreground(color term.Color, style term.Style) {
+	fmt.Fprint(a, "\033[0;")
+	if style&term.Bold == term.Bold {
+		fmt.Fprint(a, "1;")
+	}
+	if style&term.Underline == term.Underline {
+		fmt.Fprint(a, "4;")
+	}
+	if style&term.Blink == term.Blink {
+		fmt.Fprint(a, "5;")
+	}
+	if style&dim == dim {
+		fmt.Fprint(a, "2;")
+	}
+	fmt.Fprintf(a, "%dm", 30+color)
+}
+
+func validateColorMode() error {
+	switch colorMode {
+	case colorAuto, colorAlways, colorNever:
+		return nil
+	default:
+		return errs.Newf("invalid color mode %q; must be one of %s, %s, or %s", colorMode, colorAuto, colorAlways,
+			colorNever)
+	}
+}
+
+// useColor returns true if the output may contain ANSI escape sequences. In auto mode, they are used only when the
+// output is a terminal that supports them and the NO_COLOR convention (https://no-color.org) isn't in effect.
+func useColor() bool {
+	switch colorMode {
+	case colorAlways:
+		// Even when forced, give a Windows console the chance to interpret them
+		virtualTerminal()
+		return true
+	case colorNever:
+		return false
+	default:
+		return os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && isTerminal(os.Stdout) && virtualTerminal()
+	}
+}
+
+// virtualTerminal returns true if stdout interprets ANSI escape sequences, once enabling that where necessary.
+var virtualTerminal = sync.OnceValue(func() bool { return enableVirtualTerminal(os.Stdout) })
+
+// isTerminal returns true if f is a terminal. Unlike term.IsTerminal, this recognizes Windows consoles.
+func isTerminal(f *os.File) bool {
+	return rawterm.IsTerminal(int(f.Fd()))
+}
+
+// terminalSize returns the number of columns and rows of the terminal stdout is attached to, falling back to a
+// conventional size should it not be one.
+func terminalSize() (width, height int) {
+	if w, h, err := rawterm.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 && h > 0 {
+		return w, h
+	}
+	return term.Size()
+}
+
+// colorRow returns the text of a row made up of segments, as composeRow does, but with each segment in its color.
+func colorRow(segments []*msgInfo) string {
+	var buffer strings.Builder
+	t := newANSI(&buffer)
+	col := 1
+	for i, m := range segments {
+		text := []rune(firstLine(m.msg))
+		if i+1 < len(segments) {
+			// Later segments overwrite anything that would extend beneath them
+			text = text[:min(len(text), max(segments[i+1].col-m.col, 0))]
+		}
+		if m.col > col {
+			buffer.WriteString(strings.Repeat(" ", m.col-col))
+			col = m.col
+		}
+		visible := strings.TrimRight(string(text), " ")
+		t.Foreground(m.color, m.style)
+		buffer.WriteString(visible)
+		col += len([]rune(visible))
+	}
+	t.Reset()
+	return buffer.String()
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/cmdline"
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+// optionLine matches the first line of an option's entry within a command's help, capturing its single letter form,
+// name, argument, and the start of its description.
+var optionLine = regexp.MustCompile(`^(?:  -(\S), |      )--([A-Za-z][\w-]*)(?: <([^>]+)>)?\s*(.*)$`)
+
+// completionValues holds the values offered for the arguments of options that take one of a fixed set.
+var completionValues = map[string][]string{
+	"color":          {colorAuto, colorAlways, colorNever},
+	"sort":           {sortName, sortStatus, sortDuration, sortMTime, sortSize},
+	"rewrite-remote": {rewriteSSH, rewriteHTTPS},
+}
+
+// completionRepoOptions are the options whose globs are completed with the names of repos.
+var completionRepoOptions = []string{"include", "exclude"}
+
+type completionCmd struct {
+	cmds []cmdline.Cmd
+}
+
+// completionSpec describes a command, as gathered for completion.
+type completionSpec struct {
+	name    string
+	usage   string
+	options []*completionOption
+}
+
+// completionOption describes one of a command's options, as gathered from its help.
+type completionOption struct {
+	name   string
+	single string
+	arg    string // the name of the option's argument, if it takes one
+	usage  string
+}
+
+func (c *completionCmd) Name() string {
+	return "completion"
+}
+
+func (c *completionCmd) Usage() string {
+	return "Prints a script for bash, zsh, or fish that completes gp's commands and options, along with the names of " +
+		"the repos in the default paths for --include and --exclude. Source it from the shell's startup file, e.g. " +
+		"with: source <(gp completion bash)"
+}
+
+func (c *completionCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	cl.UsageSuffix = "bash|zsh|fish"
+	remaining := cl.Parse(args)
+	if len(remaining) != 1 {
+		cl.FatalMsg("A single shell must be specified")
+	}
+	shell := remaining[0]
+	switch shell {
+	case "repos":
+		// Used by the scripts to complete the names of repos
+		listRepoNames()
+		return nil
+	case "bash", "zsh", "fish":
+	default:
+		cl.FatalMsg(fmt.Sprintf("invalid shell %q; must be one of bash, zsh, or fish", shell))
+	}
+	specs, err := c.gather()
+	if err != nil {
+		cl.FatalMsg(multirepo.ErrorText(err))
+	}
+	switch shell {
+	case "bash":
+		fmt.Print(bashCompletion(specs))
+	case "zsh":
+		fmt.Print("autoload -U +X bashcompinit && bashcompinit\n" + bashCompletion(specs))
+	default:
+		fmt.Print(fishCompletion(specs))
+	}
+	return nil
+}
+
+// gather returns the commands along with their options. The command line package doesn't expose the options, which are
+// only defined as each command runs, so they are taken from each command's help instead.
+func (c *completionCmd) gather() ([]*completionSpec, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	specs := make([]*completionSpec, 0, len(c.cmds))
+	for _, cmd := range c.cmds {
+		spec := &completionSpec{name: cmd.Name(), usage: firstSentence(cmd.Usage())}
+		if cmd != c {
+			// Displaying the help exits with a failure status, so only the lack of output indicates a problem
+			out, helpErr := exec.Command(exe, cmd.Name(), "--help").CombinedOutput()
+			if len(out) == 0 {
+				return nil, errs.NewWithCause("unable to obtain the options of "+cmd.Name(), helpErr)
+			}
+			spec.options = parseHelpOptions(cmd.Name(), string(out))
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// parseHelpOptions returns the options listed in the section of help that holds those of the named command.
+func parseHelpOptions(name, help string) []*completionOption {
+	var options []*completionOption
+	var current *completionOption
+	inSection := false
+	for _, line := range strings.Split(help, "\n") {
+		if strings.TrimSpace(line) == name+" options:" {
+			inSection = true
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if m := optionLine.FindStringSubmatch(line); m != nil {
+			current = &completionOption{single: m[1], name: m[2], arg: m[3], usage: m[4]}
+			options = append(options, current)
+			continue
+		}
+		if current != nil && strings.HasPrefix(line, "        ") && strings.TrimSpace(line) != "" {
+			current.usage += " " + strings.TrimSpace(line)
+			continue
+		}
+		current = nil
+	}
+	for _, op := range options {
+		op.usage = firstSentence(op.usage)
+	}
+	return options
+}
+
+// firstSentence returns the first sentence of text, without its period.
+func firstSentence(text string) string {
+	if i := strings.Index(text, ". "); i != -1 {
+		text = text[:i]
+	}
+	return strings.TrimSuffix(strings.TrimSpace(text), ".")
+}
+
+// takesPath returns true if the option's argument is a file or directory.
+func (op *completionOption) takesPath() bool {
+	return op.arg == "file" || op.arg == "path"
+}
+
+func bashCompletion(specs []*completionSpec) string {
+	var b strings.Builder
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		names = append(names, spec.name)
+	}
+	// Options of the same name take the same kind of argument in every command
+	var valueCases, pathOptions, argOptions []string
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		for _, op := range spec.options {
+			if op.arg == "" || seen[op.name] {
+				continue
+			}
+			seen[op.name] = true
+			forms := "--" + op.name
+			if op.single != "" {
+				forms += "|-" + op.single
+			}
+			switch {
+			case slices.Contains(completionRepoOptions, op.name):
+				valueCases = append(valueCases, fmt.Sprintf("\t%s)\n"+
+					"\t\tCOMPREPLY=($(compgen -W \"$(%s completion repos 2>/dev/null)\" -- \"$cur\"))\n"+
+					"\t\treturn\n\t\t;;\n", forms, cmdline.AppCmdName))
+			case len(completionValues[op.name]) != 0:
+				valueCases = append(valueCases, fmt.Sprintf("\t%s)\n\t\tCOMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n"+
+					"\t\treturn\n\t\t;;\n", forms, strings.Join(completionValues[op.name], " ")))
+			case op.takesPath():
+				pathOptions = append(pathOptions, forms)
+			default:
+				argOptions = append(argOptions, forms)
+			}
+		}
+	}
+	fmt.Fprintf(&b, "# %s completion for bash, generated by \"%[1]s completion bash\"\n", cmdline.AppCmdName)
+	fmt.Fprintf(&b, "_%s() {\n", cmdline.AppCmdName)
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\" cmd=pull opts\n")
+	fmt.Fprintf(&b, "\tcase \"${COMP_WORDS[1]}\" in\n\t%s)\n\t\tcmd=\"${COMP_WORDS[1]}\"\n\t\t;;\n\tesac\n",
+		strings.Join(names, "|"))
+	b.WriteString("\tif [[ $COMP_CWORD -eq 1 && $cur != -* ]]; then\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W \"%s\" -- \"$cur\") $(compgen -d -- \"$cur\"))\n\t\treturn\n\tfi\n",
+		strings.Join(names, " "))
+	b.WriteString("\tcase \"$prev\" in\n")
+	for _, one := range valueCases {
+		b.WriteString(one)
+	}
+	if len(pathOptions) != 0 {
+		fmt.Fprintf(&b, "\t%s)\n\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n\t\treturn\n\t\t;;\n",
+			strings.Join(pathOptions, "|"))
+	}
+	if len(argOptions) != 0 {
+		fmt.Fprintf(&b, "\t%s)\n\t\treturn\n\t\t;;\n", strings.Join(argOptions, "|"))
+	}
+	b.WriteString("\tesac\n\tcase \"$cmd\" in\n")
+	for _, spec := range specs {
+		opts := make([]string, 0, len(spec.options))
+		for _, op := range spec.options {
+			opts = append(opts, "--"+op.name)
+		}
+		fmt.Fprintf(&b, "\t%s)\n\t\topts=\"%s\"\n\t\t;;\n", spec.name, strings.Join(opts, " "))
+	}
+	b.WriteString("\tesac\n")
+	b.WriteString("\tif [[ $cur == -* ]]; then\n\t\tCOMPREPLY=($(compgen -W \"$opts\" -- \"$cur\"))\n")
+	b.WriteString("\telse\n\t\tCOMPREPLY=($(compgen -d -- \"$cur\"))\n\tfi\n}\n")
+	fmt.Fprintf(&b, "complete -o filenames -F _%s %[1]s\n", cmdline.AppCmdName)
+	return b.String()
+}
+
+func fishCompletion(specs []*completionSpec) string {
+	var b strings.Builder
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		names = append(names, spec.name)
+	}
+	app := cmdline.AppCmdName
+	fmt.Fprintf(&b, "# %s completion for fish, generated by \"%[1]s completion fish\"\n", app)
+	fmt.Fprintf(&b, "complete -c %s -f\n", app)
+	fmt.Fprintf(&b, "complete -c %s -a '(__fish_complete_directories)'\n", app)
+	for _, spec := range specs {
+		fmt.Fprintf(&b, "complete -c %s -n __fish_use_subcommand -a %s -d %s\n", app, spec.name, fishQuote(spec.usage))
+	}
+	for _, spec := range specs {
+		cond := "__fish_seen_subcommand_from " + spec.name
+		if spec.name == "pull" {
+			// Pulling is what happens without a command
+			cond += "; or not __fish_seen_subcommand_from " + strings.Join(names, " ")
+		}
+		for _, op := range spec.options {
+			fmt.Fprintf(&b, "complete -c %s -n %s -l %s", app, fishQuote(cond), op.name)
+			if op.single != "" {
+				fmt.Fprintf(&b, " -s %s", op.single)
+			}
+			switch {
+			case op.arg == "":
+			case slices.Contains(completionRepoOptions, op.name):
+				fmt.Fprintf(&b, " -x -a '(%s completion repos 2>/dev/null)'", app)
+			case len(completionValues[op.name]) != 0:
+				fmt.Fprintf(&b, " -x -a %s", fishQuote(strings.Join(completionValues[op.name], " ")))
+			case op.takesPath():
+				b.WriteString(" -r -F")
+			default:
+				b.WriteString(" -x")
+			}
+			fmt.Fprintf(&b, " -d %s\n", fishQuote(op.usage))
+		}
+	}
+	return b.String()
+}
+
+// fishQuote returns text quoted for fish.
+func fishQuote(text string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(text, `\`, `\\`), `'`, `\'`) + "'"
+}
+
+// listRepoNames prints the paths, relative to their roots, of the repos within the default paths. Problems are ignored,
+// as there's nowhere to report them while completing.
+func listRepoNames() {
+	paths := defaultRoots()
+	if len(paths) == 0 {
+		return
+	}
+	list, locs, _, err := discover(paths)
+	if err != nil {
+		return
+	}
+	for _, p := range list {
+		fmt.Println(locs[p].rel)
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/cmdline"
+	"github.com/richardwilkes/toolbox/errs"
+	"github.com/richardwilkes/toolbox/xio/fs"
+	"github.com/richardwilkes/toolbox/xio/fs/paths"
+)
+
+const localConfigName = ".gp.yaml"
+
+const (
+	pullMerge  = "merge"
+	pullRebase = "rebase"
+	pullFFOnly = "ff-only"
+)
+
+// settings holds the options that may differ from one workspace root to another.
+type settings struct {
+	Timeout    *time.Duration `yaml:"timeout,omitempty"` // zero means no limit
+	Retries    *int           `yaml:"retries,omitempty"`
+	RetryDelay *time.Duration `yaml:"retry_delay,omitempty"`
+	Pull       string         `yaml:"pull,omitempty"`
+	Exclude    []string       `yaml:"exclude,omitempty"`
+	StaleStash time.Duration  `yaml:"stale_stash,omitempty"` // stashes older than this are reported by status
+	Stale      time.Duration  `yaml:"stale,omitempty"`       // status highlights repos with a HEAD older than this
+	PostPull   string         `yaml:"post_pull,omitempty"`
+	// PostPullRepos maps globs, matched against a repo's directory name or relative path, to the post-pull command to
+	// use for matching repos in place of PostPull
+	PostPullRepos map[string]string `yaml:"post_pull_repos,omitempty"`
+	Audit         auditPolicy       `yaml:"audit,omitempty"`
+}
+
+// config holds the contents of a configuration file.
+type config struct {
+	Paths       []string `yaml:"paths,omitempty"`
+	Jobs        int      `yaml:"jobs,omitempty"`
+	GitHubToken string   `yaml:"github_token,omitempty"`
+	GitLabToken string   `yaml:"gitlab_token,omitempty"`
+	// Colors maps kinds of output, such as "failed" or "text", to the names of the colors to display them in
+	Colors   map[string]string `yaml:"colors,omitempty"`
+	settings `yaml:",inline"`
+}
+
+// defaults holds the settings that apply to repos whose workspace root doesn't have its own configuration file.
+var defaults = settings{
+	Timeout:    newDuration(5 * time.Minute),
+	Retries:    newInt(4),
+	RetryDelay: newDuration(time.Second),
+	Pull:       pullMerge,
+}
+
+// overrides holds the settings given on the command line, which take precedence over those from configuration files.
+var overrides settings
+
+var (
+	defaultPaths []string
+	githubToken  string
+	gitlabToken  string
+)
+
+func newInt(value int) *int {
+	return &value
+}
+
+func newDuration(value time.Duration) *time.Duration {
+	return &value
+}
+
+// userConfigPath returns the path to the user's configuration file.
+func userConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(paths.HomeDir(), ".config")
+	}
+	return filepath.Join(dir, "gp", "config.yaml")
+}
+
+// loadConfigs loads the user's configuration file followed by the one in the current directory, if any, and then the
+// configuration given by environment variables, applying them as the defaults for the command line options.
+func loadConfigs() error {
+	files := []string{userConfigPath()}
+	if wd, err := os.Getwd(); err == nil {
+		files = append(files, filepath.Join(wd, localConfigName))
+	}
+	for _, file := range files {
+		cfg, err := loadConfig(file)
+		if err != nil {
+			return err
+		}
+		if cfg == nil {
+			continue
+		}
+		applyConfig(cfg)
+		defaults = defaults.merge(&cfg.settings)
+	}
+	if err := defaults.validate(); err != nil {
+		return err
+	}
+	return loadEnvironment()
+}
+
+// applyConfig sets the options given by cfg that apply to the whole run, rather than varying by workspace root.
+func applyConfig(cfg *config) {
+	if len(cfg.Paths) != 0 {
+		defaultPaths = cfg.Paths
+	}
+	if cfg.Jobs > 0 {
+		jobs = cfg.Jobs
+	}
+	if cfg.GitHubToken != "" {
+		githubToken = cfg.GitHubToken
+	}
+	if cfg.GitLabToken != "" {
+		gitlabToken = cfg.GitLabToken
+	}
+	maps.Copy(palette, cfg.Colors)
+}
+
+// loadConfig loads the configuration file at path. Returns nil if the file doesn't exist.
+func loadConfig(path string) (*config, error) {
+	if !fs.FileExists(path) {
+		return nil, nil
+	}
+	var cfg config
+	if err := fs.LoadYAML(path, &cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, errs.NewWithCause(path, err)
+	}
+	return &cfg, nil
+}
+
+func (c *config) validate() error {
+	if err := validatePalette(c.Colors); err != nil {
+		return err
+	}
+	return c.settings.validate()
+}
+
+// rootSettings returns the settings to use for repos found within root.
+func rootSettings(root string) (*settings, error) {
+	s := defaults
+	if wd, err := os.Getwd(); err != nil || filepath.Clean(root) != filepath.Clean(wd) {
+		// The current directory's configuration is already incorporated into the defaults
+		var cfg *config
+		if cfg, err = loadConfig(filepath.Join(root, localConfigName)); err != nil {
+			return nil, err
+		}
+		if cfg != nil {
+			s = s.merge(&cfg.settings)
+		}
+	}
+	s = s.merge(&environment)
+	s = s.merge(&overrides)
+	return &s, nil
+}
+
+// merge returns a copy of the settings with the values set in other applied on top. Exclusions accumulate.
+func (s settings) merge(other *settings) settings {
+	if other.Timeout != nil {
+		s.Timeout = other.Timeout
+	}
+	if other.Retries != nil {
+		s.Retries = other.Retries
+	}
+	if other.RetryDelay != nil {
+		s.RetryDelay = other.RetryDelay
+	}
+	if other.Pull != "" {
+		s.Pull = other.Pull
+	}
+	if other.StaleStash > 0 {
+		s.StaleStash = other.StaleStash
+	}
+	if other.Stale > 0 {
+		s.Stale = other.Stale
+	}
+	s.Exclude = append(append([]string(nil), s.Exclude...), other.Exclude...)
+	if other.PostPull != "" {
+		s.PostPull = other.PostPull
+	}
+	if len(other.PostPullRepos) != 0 {
+		hooks := maps.Clone(s.PostPullRepos)
+		if hooks == nil {
+			hooks = make(map[string]string)
+		}
+		maps.Copy(hooks, other.PostPullRepos)
+		s.PostPullRepos = hooks
+	}
+	if len(other.Audit.Remotes) != 0 {
+		s.Audit.Remotes = other.Audit.Remotes
+	}
+	if other.Audit.Email != "" {
+		s.Audit.Email = other.Audit.Email
+	}
+	if other.Audit.Signing {
+		s.Audit.Signing = true
+	}
+	return s
+}
+
+func (s *settings) validate() error {
+	switch s.Pull {
+	case "", pullMerge, pullRebase, pullFFOnly:
+	default:
+		return errs.Newf("invalid pull strategy %q; must be one of %s, %s, or %s", s.Pull, pullMerge, pullRebase,
+			pullFFOnly)
+	}
+	if s.Timeout != nil && *s.Timeout < 0 {
+		return errs.New("timeout may not be negative")
+	}
+	if s.Retries != nil && *s.Retries < 0 {
+		return errs.New("retries may not be negative")
+	}
+	if s.RetryDelay != nil && *s.RetryDelay < 0 {
+		return errs.New("retry delay may not be negative")
+	}
+	if s.StaleStash < 0 {
+		return errs.New("stale stash age may not be negative")
+	}
+	if s.Stale < 0 {
+		return errs.New("stale age may not be negative")
+	}
+	for pattern := range s.PostPullRepos {
+		if err := multirepo.ValidatePatterns([]string{pattern}); err != nil {
+			return err
+		}
+	}
+	if err := multirepo.ValidatePatterns(s.Audit.Remotes); err != nil {
+		return err
+	}
+	if s.Audit.Email != "" {
+		if err := multirepo.ValidatePatterns([]string{s.Audit.Email}); err != nil {
+			return err
+		}
+	}
+	return multirepo.ValidatePatterns(s.Exclude)
+}
+
+// overrideValue wraps a command line option's value, invoking apply once it has been set so that the value can be
+// recorded in the overrides.
+type overrideValue struct {
+	cmdline.GeneralValue
+	apply func()
+}
+
+// Set implements cmdline.Value.
+func (v *overrideValue) Set(value string) error {
+	if err := v.GeneralValue.Set(value); err != nil {
+		return err
+	}
+	v.apply()
+	return nil
+}
+
+// addSettingsOptions adds the options that override settings from the configuration files. Their defaults reflect the
+// configuration of the current directory and the environment.
+func addSettingsOptions(cl *cmdline.CmdLine) {
+	current := defaults.merge(&environment)
+	timeout := *current.Timeout
+	cl.NewOption(&overrideValue{
+		GeneralValue: cmdline.GeneralValue{Value: &timeout},
+		apply:        func() { overrides.Timeout = &timeout },
+	}).SetName("timeout").SetArg("duration").
+		SetUsage("The maximum time a single git command may take. Zero means no limit")
+	retries := *current.Retries
+	cl.NewOption(&overrideValue{
+		GeneralValue: cmdline.GeneralValue{Value: &retries},
+		apply:        func() { overrides.Retries = &retries },
+	}).SetName("retries").SetArg("N").
+		SetUsage("The number of times to retry a git command that failed due to a transient error, such " +
+			"as a network problem")
+	retryDelay := *current.RetryDelay
+	cl.NewOption(&overrideValue{
+		GeneralValue: cmdline.GeneralValue{Value: &retryDelay},
+		apply:        func() { overrides.RetryDelay = &retryDelay },
+	}).SetName("retry-delay").SetArg("duration").
+		SetUsage("The delay before the first retry. Each subsequent retry waits twice as long as the one before it, " +
+			"with some random variation")
+}
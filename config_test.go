@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/richardwilkes/toolbox/check"
+)
+
+func TestSettingsMergeZero(t *testing.T) {
+	s := defaults.merge(&settings{})
+	check.Equal(t, 5*time.Minute, *s.Timeout)
+	check.Equal(t, time.Second, *s.RetryDelay)
+	check.Equal(t, 4, *s.Retries)
+
+	// Zero is a value in its own right, turning off the timeout and the delay between retries
+	s = s.merge(&settings{Timeout: newDuration(0), Retries: newInt(0), RetryDelay: newDuration(0)})
+	check.Equal(t, time.Duration(0), *s.Timeout)
+	check.Equal(t, time.Duration(0), *s.RetryDelay)
+	check.Equal(t, 0, *s.Retries)
+	check.Equal(t, 5*time.Minute, *defaults.Timeout, "the settings merged into must be left unchanged")
+
+	check.Error(t, (&settings{Timeout: newDuration(-time.Second)}).validate())
+	check.Error(t, (&settings{RetryDelay: newDuration(-time.Second)}).validate())
+}
+
+func TestEnvironmentZero(t *testing.T) {
+	saved := environment
+	defer func() { environment = saved }()
+	t.Setenv("GP_TIMEOUT", "0")
+	t.Setenv("GP_RETRY_DELAY", "0s")
+	check.NoError(t, loadEnvironment())
+	s := defaults.merge(&environment)
+	check.Equal(t, time.Duration(0), *s.Timeout)
+	check.Equal(t, time.Duration(0), *s.RetryDelay)
+
+	t.Setenv("GP_TIMEOUT", "")
+	t.Setenv("GP_RETRY_DELAY", "")
+	check.NoError(t, loadEnvironment())
+	check.Nil(t, environment.Timeout)
+	check.Nil(t, environment.RetryDelay)
+}
+
+func TestConfigFileZero(t *testing.T) {
+	path := filepath.Join(t.TempDir(), localConfigName)
+	check.NoError(t, os.WriteFile(path, []byte("timeout: 0s\nretry_delay: 0s\n"), 0o644))
+	cfg, err := loadConfig(path)
+	check.NoError(t, err)
+	s := defaults.merge(&cfg.settings)
+	check.Equal(t, time.Duration(0), *s.Timeout)
+	check.Equal(t, time.Duration(0), *s.RetryDelay)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/atexit"
+	"github.com/richardwilkes/toolbox/cmdline"
+	"github.com/richardwilkes/toolbox/errs"
+	"github.com/richardwilkes/toolbox/xio"
+)
+
+// cycleDone, if set, is called with the repos each time a run of them completes.
+var cycleDone func(repos []*repo)
+
+type daemonCmd struct {
+	socket string
+}
+
+// daemonStatus is the document served by the daemon's status endpoint.
+type daemonStatus struct {
+	Synced  time.Time           `json:"synced"`
+	Results []*multirepo.Result `json:"results"`
+}
+
+type statusServer struct {
+	lock   sync.RWMutex
+	status []byte
+}
+
+func (c *daemonCmd) Name() string {
+	return "daemon"
+}
+
+func (c *daemonCmd) Usage() string {
+	return "Pulls continuously on an interval, serving the latest results as JSON over a local " +
+		"socket. Given the argument \"status\", queries a running daemon for them instead."
+}
+
+func (c *daemonCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	watch = 15 * time.Minute
+	addCommonOptions(cl)
+	cl.UsageSuffix = "[status] " + pathsUsage
+	c.socket = defaultSocketPath()
+	cl.NewGeneralOption(&c.socket).SetName("socket").SetArg("path").
+		SetUsage("The path of the Unix socket on which the status is served")
+	paths := cl.Parse(args)
+	if len(paths) != 0 && paths[0] == "status" {
+		if err := c.query(paths[1:]); err != nil {
+			cl.FatalMsg(multirepo.ErrorText(err))
+		}
+		return nil
+	}
+	if watch <= 0 {
+		cl.FatalMsg("--watch must be greater than zero")
+	}
+	server, err := c.serve()
+	if err != nil {
+		cl.FatalMsg(multirepo.ErrorText(err))
+	}
+	cycleDone = server.publish
+	// The daemon's output is a log rather than a display
+	plain = true
+	run(paths, pullRepo)
+	return nil
+}
+
+// defaultSocketPath returns the socket path to use when none has been specified.
+func defaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "gp.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("gp-%d.sock", os.Getuid()))
+}
+
+// serve starts serving the status on the socket, which is removed upon exit.
+func (c *daemonCmd) serve() (*statusServer, error) {
+	if _, err := os.Stat(c.socket); err == nil {
+		if conn, dialErr := net.Dial("unix", c.socket); dialErr == nil {
+			xio.CloseIgnoringErrors(conn)
+			return nil, errs.New("a daemon is already running on " + c.socket)
+		}
+		// Left behind by a daemon that didn't exit cleanly
+		if err = os.Remove(c.socket); err != nil {
+			return nil, errs.NewWithCause("unable to remove stale socket "+c.socket, err)
+		}
+	}
+	listener, err := net.Listen("unix", c.socket)
+	if err != nil {
+		return nil, errs.NewWithCause("unable to listen on "+c.socket, err)
+	}
+	atexit.Register(func() {
+		xio.CloseIgnoringErrors(listener)
+		if removeErr := os.Remove(c.socket); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+			fmt.Fprintln(os.Stderr,
+				multirepo.ErrorText(errs.NewWithCause("unable to remove socket "+c.socket, removeErr)))
+		}
+	})
+	s := &statusServer{status: []byte("null\n")}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handleStatus)
+	go func() {
+		if serveErr := http.Serve(listener, mux); serveErr != nil && !errors.Is(serveErr, net.ErrClosed) {
+			fmt.Fprintln(os.Stderr, multirepo.ErrorText(errs.NewWithCause("status server failed", serveErr)))
+		}
+	}()
+	return s, nil
+}
+
+// publish records the results of the repos as the latest status.
+func (s *statusServer) publish(repos []*repo) {
+	status := daemonStatus{Synced: time.Now(), Results: make([]*multirepo.Result, len(repos))}
+	for i, r := range repos {
+		status.Results[i] = &r.result
+	}
+	data, err := json.MarshalIndent(&status, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, multirepo.ErrorText(errs.Wrap(err)))
+		return
+	}
+	s.lock.Lock()
+	s.status = append(data, '\n')
+	s.lock.Unlock()
+}
+
+func (s *statusServer) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	s.lock.RLock()
+	data := s.status
+	s.lock.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// query retrieves the status from a running daemon and prints it.
+func (c *daemonCmd) query(extra []string) error {
+	if len(extra) != 0 {
+		return errs.New("no paths may be given when querying the status")
+	}
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", c.socket)
+			},
+		},
+	}
+	rsp, err := client.Get("http://gp/status")
+	if err != nil {
+		return errs.NewWithCause("unable to reach a daemon on "+c.socket, err)
+	}
+	defer xio.CloseIgnoringErrors(rsp.Body)
+	if rsp.StatusCode != http.StatusOK {
+		return errs.New("daemon responded with " + rsp.Status)
+	}
+	if _, err = io.Copy(os.Stdout, rsp.Body); err != nil {
+		return errs.Wrap(err)
+	}
+	return nil
+}
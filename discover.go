@@ -0,0 +1,92 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/txt"
+)
+
+var (
+	includes []string
+	excludes []string
+	// refresh forces the repos to be found by scanning, rather than from the discovery cache.
+	refresh bool
+	// bare includes bare repos, such as mirrors, when searching for repos.
+	bare bool
+)
+
+// location holds what is known about a repo prior to processing it.
+type location struct {
+	cfg     *settings
+	rel     string // path relative to the root it was found within, using forward slashes
+	url     string // only known for repos listed in a manifest
+	missing bool   // true if the repo was listed in a manifest but isn't present
+	bare    bool   // true if the repo is a bare repo, which has no checkout
+}
+
+// discover returns the sorted list of git repos found within paths and what is known about each, along with the root
+// to use when computing display names. Unless --refresh was given, the results of earlier scans are reused for the
+// paths in which nothing has changed since.
+func discover(paths []string) (list []string, locs map[string]*location, root string, err error) {
+	locs = make(map[string]*location)
+	for _, path := range paths {
+		var s *settings
+		if s, err = rootSettings(path); err != nil {
+			return nil, nil, "", err
+		}
+		for _, found := range multirepo.Discover(path, &multirepo.DiscoverOptions{
+			Depth:     depth,
+			Include:   includes,
+			Exclude:   append(slices.Clip(s.Exclude), excludes...),
+			CachePath: discoveryCachePath(),
+			Refresh:   refresh,
+			Bare:      bare,
+		}) {
+			if _, exists := locs[found.Path]; !exists {
+				locs[found.Path] = &location{cfg: s, rel: found.Rel, bare: found.Bare}
+			}
+		}
+	}
+	if len(paths) == 1 {
+		if root, err = multirepo.RealPath(paths[0]); err != nil {
+			root = paths[0]
+		}
+	}
+	return sortedPaths(locs), locs, root, nil
+}
+
+func discoveryCachePath() string {
+	return filepath.Join(filepath.Dir(userConfigPath()), "discovery.json")
+}
+
+func sortedPaths(locs map[string]*location) []string {
+	list := make([]string, 0, len(locs))
+	for p := range locs {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return txt.NaturalLess(list[i], list[j], true) })
+	return list
+}
+
+func isExcluded(s *settings, root, path string) bool {
+	return multirepo.MatchesAny(s.Exclude, root, path) || multirepo.MatchesAny(excludes, root, path)
+}
+
+func isIncluded(root, path string) bool {
+	return len(includes) == 0 || multirepo.MatchesAny(includes, root, path)
+}
+
+// displayName returns the name to show for the repo at path. When root is empty, the full path is used.
+func displayName(root, path string) string {
+	if root != "" {
+		if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+		return filepath.Base(path)
+	}
+	return path
+}
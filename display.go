@@ -0,0 +1,325 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/xio/term"
+)
+
+var (
+	// liveProgress is true when the display is updated in place, allowing the progress of long-running commands to be
+	// shown as it happens.
+	liveProgress bool
+	// onlyChanged leaves the repos that were left unchanged, without anything deserving attention, out of the display.
+	onlyChanged bool
+)
+
+type msgInfo struct {
+	msg   string
+	row   int
+	col   int
+	color term.Color
+	style term.Style
+	done  bool // true once the repo for this row has finished processing
+
+	started    bool              // true once the repo for this row has begun processing
+	outcome    multirepo.Outcome // the repo's outcome, once done
+	transcript bool              // true if msg is the output of a command run for the repo, not something to display
+	header     bool              // true if the row is the header of a group of repos, rather than a repo
+	heading    int               // for done messages, the row of the repo's group header, if grouped
+	quiet      bool              // for done messages, true if the row is left out of the display, with --only-changed
+}
+
+// processMsgs updates the display in place, positioning each message at its row and column. Should the terminal be
+// resized such that the rows no longer fit, it falls back to printing each row once its repo has finished, as
+// processPlainMsgs does.
+func processMsgs(wg *sync.WaitGroup, t *ansi, printer chan *msgInfo) {
+	defer wg.Done()
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+	defer signal.Stop(resized)
+	d := &inPlaceDisplay{
+		t:       t,
+		rows:    make(map[int][]*msgInfo),
+		done:    make(map[int]bool),
+		running: make(map[int]time.Time),
+		quiet:   make(map[int]bool),
+		heading: make(map[int]int),
+		maxRow:  1,
+	}
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+	d.width, d.height = terminalSize()
+	// Credential prompts take over the screen while they wait for an answer, after which it must be redrawn. Holding
+	// the lock while drawing keeps the two from being interleaved.
+	prompts.lock.Lock()
+	prompts.active = true
+	prompts.redraw = resized
+	prompts.lock.Unlock()
+	defer func() {
+		prompts.lock.Lock()
+		prompts.active = false
+		prompts.lock.Unlock()
+	}()
+	for {
+		select {
+		case m, ok := <-printer:
+			if !ok {
+				t.Reset()
+				if !d.appendOnly {
+					if len(d.quiet) != 0 {
+						d.collapse()
+					} else {
+						t.Position(d.maxRow+1, 1)
+					}
+				}
+				return
+			}
+			prompts.lock.Lock()
+			d.handleMsg(m)
+			prompts.lock.Unlock()
+		case <-resized:
+			prompts.lock.Lock()
+			d.redraw()
+			prompts.lock.Unlock()
+		case <-ticker.C:
+			prompts.lock.Lock()
+			d.drawSpinners()
+			prompts.lock.Unlock()
+		}
+	}
+}
+
+// inPlaceDisplay holds the state of the display that processMsgs updates in place.
+type inPlaceDisplay struct {
+	t           *ansi
+	rows        map[int][]*msgInfo
+	done        map[int]bool
+	running     map[int]time.Time // when each repo still being processed began
+	quiet       map[int]bool      // the rows to be left out of the display once all have finished, with --only-changed
+	heading     map[int]int       // the row of each finished repo's group header, if grouped
+	maxRow      int
+	width       int
+	height      int
+	appendOnly  bool
+	lastHeading int
+}
+
+func (d *inPlaceDisplay) handleMsg(m *msgInfo) {
+	if m.transcript {
+		return
+	}
+	if m.started {
+		d.running[m.row] = time.Now()
+		return
+	}
+	if m.done {
+		d.done[m.row] = true
+		delete(d.running, m.row)
+		if m.quiet {
+			d.quiet[m.row] = true
+		}
+		if m.heading != 0 {
+			d.heading[m.row] = m.heading
+		}
+		if d.appendOnly && !m.quiet {
+			printHeading(d.rows, m.heading, &d.lastHeading, true)
+			fmt.Println(colorRow(d.rows[m.row]))
+		}
+		return
+	}
+	if d.maxRow < m.row {
+		d.maxRow = m.row
+	}
+	if m.header {
+		// Headers don't change, so they can be printed as soon as the display becomes append-only
+		d.done[m.row] = true
+	}
+	d.rows[m.row] = addSegment(d.rows[m.row], m)
+	if !d.appendOnly {
+		drawSegment(d.t, m, d.width)
+		d.t.EraseLineToEnd()
+	}
+}
+
+// redraw redisplays all of the rows, as is needed after the terminal has been resized or taken over by a prompt.
+func (d *inPlaceDisplay) redraw() {
+	if d.appendOnly {
+		return
+	}
+	d.width, d.height = terminalSize()
+	d.t.Reset()
+	d.t.Clear()
+	if d.maxRow >= d.height {
+		d.appendOnly = true
+		for row := 1; row <= d.maxRow; row++ {
+			if d.done[row] && !d.quiet[row] {
+				fmt.Println(colorRow(d.rows[row]))
+				if len(d.rows[row]) != 0 && d.rows[row][0].header {
+					d.lastHeading = row
+				}
+			}
+		}
+		return
+	}
+	for row := 1; row <= d.maxRow; row++ {
+		for _, m := range d.rows[row] {
+			drawSegment(d.t, m, d.width)
+		}
+		d.t.EraseLineToEnd()
+	}
+}
+
+// collapse redisplays the rows once all have finished, leaving out the quiet ones, along with the headers of groups
+// left without any rows.
+func (d *inPlaceDisplay) collapse() {
+	shown := make(map[int]bool)
+	for row := 1; row <= d.maxRow; row++ {
+		if len(d.rows[row]) != 0 && !d.rows[row][0].header && !d.quiet[row] {
+			shown[row] = true
+			if d.heading[row] != 0 {
+				shown[d.heading[row]] = true
+			}
+		}
+	}
+	d.t.Clear()
+	d.t.Position(1, 1)
+	for row := 1; row <= d.maxRow; row++ {
+		if shown[row] {
+			fmt.Println(colorRow(d.rows[row]))
+		}
+	}
+}
+
+// drawSpinners displays a spinner at the end of each row whose repo is still being processed. They aren't retained as
+// part of the rows, so the next segment drawn on a row erases its spinner until the following tick.
+func (d *inPlaceDisplay) drawSpinners() {
+	if d.appendOnly {
+		return
+	}
+	now := time.Now()
+	for row, start := range d.running {
+		drawSegment(d.t, spinner(row, d.rows[row], start, now), d.width)
+		d.t.EraseLineToEnd()
+	}
+}
+
+// drawSegment displays m at its position, truncated so that it doesn't wrap past the width of the terminal.
+func drawSegment(t *ansi, m *msgInfo, width int) {
+	t.Foreground(m.color, m.style)
+	t.Position(m.row, m.col)
+	fmt.Print(truncate(firstLine(m.msg), max(width-m.col, 0)))
+}
+
+// addSegment returns the segments of a row with m added, discarding any that m overwrites, just as the terminal
+// display does.
+func addSegment(segments []*msgInfo, m *msgInfo) []*msgInfo {
+	i := 0
+	for i < len(segments) && segments[i].col < m.col {
+		i++
+	}
+	return append(segments[:i], m)
+}
+
+// composeRow returns the plain text of a row made up of segments.
+func composeRow(segments []*msgInfo) string {
+	var line []rune
+	for _, m := range segments {
+		line = placeText(line, m.col, firstLine(m.msg))
+	}
+	return strings.TrimRight(string(line), " ")
+}
+
+// processPlainMsgs is the line-oriented alternative to processMsgs, used when output isn't going to a terminal or color
+// is disabled. Each row is composed in memory the same way it would be on screen and is printed once its repo has
+// finished, in color if colored is true. If deferred is not nil, the rows are instead stored in it by row number, to be
+// printed once all of the repos have finished.
+func processPlainMsgs(wg *sync.WaitGroup, printer chan *msgInfo, colored bool, deferred map[int]string) {
+	defer wg.Done()
+	rows := make(map[int][]*msgInfo)
+	lastHeading := 0
+	for m := range printer {
+		if m.transcript || m.started {
+			continue
+		}
+		if m.done {
+			if m.quiet {
+				delete(rows, m.row)
+				continue
+			}
+			prompts.lock.Lock()
+			if deferred != nil {
+				if m.heading != 0 {
+					deferred[m.heading] = rowText(rows[m.heading], colored)
+				}
+				deferred[m.row] = rowText(rows[m.row], colored)
+				prompts.lock.Unlock()
+				continue
+			}
+			printHeading(rows, m.heading, &lastHeading, colored)
+			fmt.Println(rowText(rows[m.row], colored))
+			delete(rows, m.row)
+			prompts.lock.Unlock()
+			continue
+		}
+		rows[m.row] = addSegment(rows[m.row], m)
+	}
+}
+
+// printHeading prints the group header at row heading, unless it was the last one printed. Repos finish in any order,
+// so a group's header is repeated whenever one of its repos follows one from another group.
+func printHeading(rows map[int][]*msgInfo, heading int, lastHeading *int, colored bool) {
+	if heading != 0 && heading != *lastHeading {
+		fmt.Println(rowText(rows[heading], colored))
+		*lastHeading = heading
+	}
+}
+
+// rowText returns the text of a row made up of segments, in color if colored is true.
+func rowText(segments []*msgInfo, colored bool) string {
+	if colored {
+		return colorRow(segments)
+	}
+	return composeRow(segments)
+}
+
+// discardMsgs drains the printer without displaying anything.
+func discardMsgs(wg *sync.WaitGroup, printer chan *msgInfo) {
+	defer wg.Done()
+	for range printer {
+		// Nothing to do
+	}
+}
+
+// placeText returns line with text written starting at the 1-based column col, discarding anything that followed, just
+// as the terminal display does.
+func placeText(line []rune, col int, text string) []rune {
+	for len(line) < col-1 {
+		line = append(line, ' ')
+	}
+	return append(line[:col-1], []rune(text)...)
+}
+
+func firstLine(msg string) string {
+	if i := strings.Index(msg, "\n"); i != -1 {
+		return msg[:i]
+	}
+	return msg
+}
+
+// truncate returns text limited to width runes, ending with an ellipsis if anything had to be removed.
+func truncate(text string, width int) string {
+	if runes := []rune(text); len(runes) > width {
+		if width < 1 {
+			return ""
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/richardwilkes/toolbox/cmdline"
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+type duCmd struct{}
+
+func (c *duCmd) Name() string {
+	return "du"
+}
+
+func (c *duCmd) Usage() string {
+	return "Reports how much disk space each repo uses, split between its working tree and " +
+		"its git directory, largest first."
+}
+
+func (c *duCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	locking = false
+	recording = false
+	// The report is meant to be read in order of size, which only line-oriented output can be printed in
+	sortBy = sortSize
+	plain = true
+	addCommonOptions(cl)
+	run(cl.Parse(args), duRepo)
+	return nil
+}
+
+func duRepo(r *repo) {
+	if !r.showBranch() {
+		return
+	}
+	gitDir, err := r.gitActual("rev-parse", "--absolute-git-dir")
+	if err != nil {
+		r.fail("error", err)
+		return
+	}
+	if r.result.GitSize, err = r.dirSize(gitDir, false); err != nil {
+		r.fail("error", err)
+		return
+	}
+	if r.result.LFSSize, err = r.dirSize(filepath.Join(gitDir, "lfs"), false); err != nil {
+		r.fail("error", err)
+		return
+	}
+	if r.result.WorktreeSize, err = r.dirSize(r.path, true); err != nil {
+		r.fail("error", err)
+		return
+	}
+	msg := formatSize(r.result.WorktreeSize+r.result.GitSize) + ": " + formatSize(r.result.WorktreeSize) +
+		" working tree, " + formatSize(r.result.GitSize) + " .git"
+	if r.result.LFSSize != 0 {
+		msg += " (" + formatSize(r.result.LFSSize) + " LFS)"
+	}
+	r.succeeded(msg)
+}
+
+// dirSize returns the total size of the files within dir, which need not exist. If worktree is true, the git metadata
+// of the checkout and any repos nested within it are left out, though the working trees of submodules are included.
+func (r *repo) dirSize(dir string, worktree bool) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if r.ctx.Err() != nil {
+			return r.ctx.Err()
+		}
+		if worktree {
+			if d.Name() == ".git" {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() && path != dir {
+				if fi, statErr := os.Lstat(filepath.Join(path, ".git")); statErr == nil && fi.IsDir() {
+					return fs.SkipDir
+				}
+			}
+		}
+		if d.Type().IsRegular() {
+			fi, infoErr := d.Info()
+			if infoErr != nil {
+				return infoErr
+			}
+			size += fi.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errs.Wrap(err)
+	}
+	return size, nil
+}
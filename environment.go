@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+const envPrefix = "GP_"
+
+// environment holds the settings given by environment variables, which take precedence over those from configuration
+// files but not over those given on the command line.
+var environment settings
+
+// loadEnvironment applies the configuration given by environment variables, each named for the corresponding key of
+// the configuration file in uppercase with a GP_ prefix, such as GP_RETRY_DELAY. GP_PATHS holds a list of paths
+// separated as PATH is, while GP_EXCLUDE holds a comma-separated list of globs.
+func loadEnvironment() error {
+	var cfg config
+	if value := os.Getenv(envPrefix + "PATHS"); value != "" {
+		cfg.Paths = filepath.SplitList(value)
+	}
+	if err := envInt("JOBS", &cfg.Jobs); err != nil {
+		return err
+	}
+	cfg.GitHubToken = os.Getenv(envPrefix + "GITHUB_TOKEN")
+	cfg.GitLabToken = os.Getenv(envPrefix + "GITLAB_TOKEN")
+	for name, d := range map[string]*time.Duration{
+		"STALE_STASH": &cfg.StaleStash,
+		"STALE":       &cfg.Stale,
+	} {
+		if err := envDuration(name, d); err != nil {
+			return err
+		}
+	}
+	// These are only set when given, so that a value of zero can take effect
+	for name, d := range map[string]**time.Duration{
+		"TIMEOUT":     &cfg.Timeout,
+		"RETRY_DELAY": &cfg.RetryDelay,
+	} {
+		if value := os.Getenv(envPrefix + name); value != "" {
+			var duration time.Duration
+			if err := envDuration(name, &duration); err != nil {
+				return err
+			}
+			*d = &duration
+		}
+	}
+	if value := os.Getenv(envPrefix + "RETRIES"); value != "" {
+		var retries int
+		if err := envInt("RETRIES", &retries); err != nil {
+			return err
+		}
+		cfg.Retries = &retries
+	}
+	cfg.Pull = os.Getenv(envPrefix + "PULL")
+	if value := os.Getenv(envPrefix + "EXCLUDE"); value != "" {
+		for _, pattern := range strings.Split(value, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				cfg.Exclude = append(cfg.Exclude, pattern)
+			}
+		}
+	}
+	cfg.PostPull = os.Getenv(envPrefix + "POST_PULL")
+	if err := cfg.validate(); err != nil {
+		return errs.NewWithCause("invalid environment configuration", err)
+	}
+	applyConfig(&cfg)
+	environment = cfg.settings
+	return nil
+}
+
+func envInt(name string, value *int) error {
+	if s := os.Getenv(envPrefix + name); s != "" {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return errs.NewWithCause(envPrefix+name+" must be a whole number", err)
+		}
+		*value = n
+	}
+	return nil
+}
+
+func envDuration(name string, value *time.Duration) error {
+	if s := os.Getenv(envPrefix + name); s != "" {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return errs.NewWithCause(envPrefix+name+" must be a duration, such as 90s or 5m", err)
+		}
+		*value = d
+	}
+	return nil
+}
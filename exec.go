@@ -0,0 +1,59 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/richardwilkes/toolbox/cmdline"
+)
+
+type execCmd struct{}
+
+func (c *execCmd) Name() string {
+	return "exec"
+}
+
+func (c *execCmd) Usage() string {
+	return "Runs a command in every git repo, exiting with a non-zero status if it fails in any of them. Within the " +
+		"command, {path}, {name}, {branch}, and {remote_url} are replaced with the repo's path, directory name, " +
+		"current branch, and origin URL."
+}
+
+func (c *execCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	addCommonOptions(cl)
+	cl.UsageSuffix = pathsUsage + " -- <command> [command arguments]"
+	// The command line parser doesn't distinguish arguments before and after "--", so split them off first
+	i := slices.Index(args, "--")
+	if i == -1 || i == len(args)-1 {
+		cl.Parse(args)
+		cl.FatalMsg("A command to execute must be specified after --")
+	}
+	command := args[i+1:]
+	run(cl.Parse(args[:i]), func(r *repo) {
+		if !r.showBranch() {
+			return
+		}
+		expanded := make([]string, len(command))
+		for j, arg := range command {
+			// Each argument is passed to the command as is, so there is no shell to guard against
+			expanded[j] = r.expandTemplate(arg, nil)
+		}
+		out, err := r.run(expanded[0], expanded[1:]...)
+		if err != nil {
+			// The last line of output is usually the most relevant when a command fails
+			prefix := "failed"
+			if out != "" {
+				lines := strings.Split(out, "\n")
+				prefix = strings.TrimSpace(lines[len(lines)-1])
+			}
+			r.fail(prefix, err)
+			return
+		}
+		if out == "" {
+			out = "done"
+		}
+		r.succeeded(out)
+	})
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/richardwilkes/toolbox/cmdline"
+)
+
+type fetchCmd struct{}
+
+func (c *fetchCmd) Name() string {
+	return "fetch"
+}
+
+func (c *fetchCmd) Usage() string {
+	return "Fetches from all remotes without merging and reports ahead/behind counts."
+}
+
+func (c *fetchCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	addCommonOptions(cl)
+	addTagsOption(cl)
+	cl.NewGeneralOption(&remoteName).SetName("remote").SetArg("name").
+		SetUsage("Fetch from just the remote, comparing against the branch of the same name there " +
+			"rather than the branch's upstream")
+	run(cl.Parse(args), fetchRepo)
+	return nil
+}
+
+func fetchRepo(r *repo) {
+	if !r.showBranch() {
+		return
+	}
+	args := []string{"fetch", "--all", "--prune"}
+	if remoteName != "" {
+		args = []string{"fetch", "--prune", remoteName}
+	}
+	if _, err := r.gitWithProgress(append(args, tagArgs()...)...); err != nil {
+		r.fail("failed to fetch", err)
+		return
+	}
+	if !r.trackUpstream() {
+		r.notice("no upstream")
+		return
+	}
+	switch {
+	case r.result.Ahead == 0 && r.result.Behind == 0:
+		r.succeeded("up to date")
+	case r.result.Behind == 0:
+		r.notice("ahead of upstream")
+	case r.result.Ahead == 0:
+		r.notice("behind upstream")
+	default:
+		r.notice("diverged from upstream")
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/richardwilkes/toolbox/cmdline"
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+type fsckCmd struct{}
+
+// packLimit is the size, in MiB, above which a packfile is reported as oversized.
+var packLimit = 1024
+
+func (c *fsckCmd) Name() string {
+	return "fsck"
+}
+
+func (c *fsckCmd) Usage() string {
+	return "Checks the repos for corrupt objects, broken refs, and oversized packfiles, reporting " +
+		"those that need attention."
+}
+
+func (c *fsckCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	locking = false
+	recording = false
+	addCommonOptions(cl)
+	cl.NewGeneralOption(&packLimit).SetName("pack-limit").SetArg("MiB").
+		SetUsage("Report packfiles larger than this. Zero disables the check")
+	run(cl.Parse(args), fsckRepo)
+	return nil
+}
+
+func fsckRepo(r *repo) {
+	if !r.showBranch() {
+		return
+	}
+	var problems []string
+	out, fsckErr := r.gitActual("fsck", "--no-dangling", "--no-progress")
+	var brokenRefs, corrupt bool
+	for _, line := range strings.Split(out, "\n") {
+		lower := strings.ToLower(line)
+		switch {
+		case strings.Contains(lower, "refs/") || strings.Contains(lower, "bad ref") ||
+			strings.Contains(lower, "invalid sha1 pointer"):
+			brokenRefs = true
+		case strings.HasPrefix(lower, "error") || strings.HasPrefix(lower, "missing") ||
+			strings.HasPrefix(lower, "broken link") || strings.HasPrefix(lower, "bad") ||
+			strings.HasPrefix(lower, "fatal"):
+			corrupt = true
+		default:
+		}
+	}
+	if corrupt {
+		problems = append(problems, "corrupt objects")
+	}
+	if brokenRefs {
+		problems = append(problems, "broken refs")
+	}
+	if fsckErr != nil && len(problems) == 0 {
+		r.fail("fsck failed", fsckErr)
+		return
+	}
+	if packLimit > 0 {
+		count, err := r.oversizedPacks(int64(packLimit) << 20)
+		if err != nil {
+			r.fail("error", err)
+			return
+		}
+		if count != 0 {
+			problems = append(problems, fmt.Sprintf("%d oversized %s", count, plural(count, "packfile", "packfiles")))
+		}
+	}
+	if len(problems) != 0 {
+		r.fail("needs attention", errs.New(strings.Join(problems, ", ")))
+		return
+	}
+	r.succeeded("healthy")
+}
+
+// oversizedPacks returns the number of the repo's packfiles that are larger than limit bytes.
+func (r *repo) oversizedPacks(limit int64) (int, error) {
+	dir, err := r.gitActual("rev-parse", "--git-path", "objects/pack")
+	if err != nil {
+		return 0, err
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(r.path, dir)
+	}
+	var packs []string
+	if packs, err = filepath.Glob(filepath.Join(dir, "*.pack")); err != nil {
+		return 0, errs.Wrap(err)
+	}
+	var count int
+	for _, pack := range packs {
+		if fi, statErr := os.Stat(pack); statErr == nil && fi.Size() > limit {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/richardwilkes/toolbox/cmdline"
+)
+
+type githubCmd struct {
+	dir             string
+	apiURL          string
+	useSSH          bool
+	includeArchived bool
+	includeForks    bool
+}
+
+type githubRepo struct {
+	Name     string `json:"name"`
+	CloneURL string `json:"clone_url"`
+	SSHURL   string `json:"ssh_url"`
+	Archived bool   `json:"archived"`
+	Fork     bool   `json:"fork"`
+}
+
+func (c *githubCmd) Name() string {
+	return "github"
+}
+
+func (c *githubCmd) Usage() string {
+	return "Mirrors the repos of a GitHub organization or user, cloning those that are missing and pulling the rest."
+}
+
+func (c *githubCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage() + " The API token is taken from the github_token configuration setting or, failing " +
+		"that, the GITHUB_TOKEN or GH_TOKEN environment variables."
+	addCommonOptions(cl)
+	cl.UsageSuffix = "<organization or user>"
+	c.apiURL = "https://api.github.com"
+	cl.NewGeneralOption(&c.dir).SetName("dir").SetArg("path").
+		SetUsage("The directory to mirror the repos into. Defaults to the current directory")
+	cl.NewGeneralOption(&c.apiURL).SetName("api-url").SetArg("url").
+		SetUsage("The base URL of the GitHub API, for use with GitHub Enterprise")
+	cl.NewGeneralOption(&c.useSSH).SetName("ssh").
+		SetUsage("Clone using SSH URLs rather than HTTPS")
+	cl.NewGeneralOption(&c.includeArchived).SetName("archived").
+		SetUsage("Include archived repos")
+	cl.NewGeneralOption(&c.includeForks).SetName("forks").
+		SetUsage("Include forked repos")
+	addDryRunOption(cl)
+	remaining := cl.Parse(args)
+	if len(remaining) != 1 {
+		cl.FatalMsg("A single organization or user must be specified")
+	}
+	owner := remaining[0]
+	cloneMissing = true
+	process([]string{c.dir}, hostedSource(c.dir, func(ctx context.Context) ([]hostedRepo, error) {
+		return c.list(ctx, owner)
+	}), pullRepo)
+	return nil
+}
+
+// list returns the repos owned by owner, trying it first as an organization and then as a user. The private repos of
+// the authenticated user are only listed when asking for them as that user, so that is done when owner is them.
+func (c *githubCmd) list(ctx context.Context, owner string) ([]hostedRepo, error) {
+	headers := map[string]string{
+		"Accept":               "application/vnd.github+json",
+		"X-GitHub-Api-Version": "2022-11-28",
+	}
+	token := tokenOr(githubToken, "GITHUB_TOKEN", "GH_TOKEN")
+	if token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+	base := strings.TrimSuffix(c.apiURL, "/")
+	var repos []githubRepo
+	err := fetchAllPages(ctx, fmt.Sprintf("%s/orgs/%s/repos?per_page=100", base, url.PathEscape(owner)), headers,
+		decodePage(&repos))
+	if err != nil {
+		repos = nil
+		userURL := fmt.Sprintf("%s/users/%s/repos?per_page=100", base, url.PathEscape(owner))
+		if token != "" && strings.EqualFold(githubLogin(ctx, base, headers), owner) {
+			userURL = base + "/user/repos?affiliation=owner&per_page=100"
+		}
+		if userErr := fetchAllPages(ctx, userURL, headers, decodePage(&repos)); userErr != nil {
+			return nil, err
+		}
+	}
+	list := make([]hostedRepo, 0, len(repos))
+	for _, one := range repos {
+		if (one.Archived && !c.includeArchived) || (one.Fork && !c.includeForks) {
+			continue
+		}
+		u := one.CloneURL
+		if c.useSSH {
+			u = one.SSHURL
+		}
+		list = append(list, hostedRepo{path: one.Name, url: u})
+	}
+	return list, nil
+}
+
+// githubLogin returns the login of the user the request headers authenticate as, or an empty string if that can't be
+// determined.
+func githubLogin(ctx context.Context, base string, headers map[string]string) string {
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := fetchAllPages(ctx, base+"/user", headers, func(data []byte) error {
+		return json.Unmarshal(data, &user)
+	}); err != nil {
+		return ""
+	}
+	return user.Login
+}
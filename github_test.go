@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/richardwilkes/toolbox/check"
+)
+
+func TestTokenOr(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "generic")
+	check.Equal(t, "gp", tokenOr("gp", "GITHUB_TOKEN", "GH_TOKEN"), "the token configured for gp must win")
+	check.Equal(t, "generic", tokenOr("", "GITHUB_TOKEN", "GH_TOKEN"))
+	t.Setenv("GH_TOKEN", "")
+	check.Equal(t, "", tokenOr("", "GITHUB_TOKEN", "GH_TOKEN"))
+}
+
+func TestGitHubListAuthenticatedUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/user" && req.Header.Get("Authorization") == "Bearer secret":
+			_, _ = w.Write([]byte(`{"login":"Me"}`))
+		case req.URL.Path == "/user/repos" && req.URL.Query().Get("affiliation") == "owner":
+			_, _ = w.Write([]byte(`[{"name":"public","clone_url":"https://example.com/me/public.git"},` +
+				`{"name":"private","clone_url":"https://example.com/me/private.git"}]`))
+		case req.URL.Path == "/users/me/repos":
+			_, _ = w.Write([]byte(`[{"name":"public","clone_url":"https://example.com/me/public.git"}]`))
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+	savedToken := githubToken
+	defer func() { githubToken = savedToken }()
+	c := &githubCmd{apiURL: server.URL}
+
+	// Without a token, only the public repos can be listed
+	githubToken = ""
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	list, err := c.list(context.Background(), "me")
+	check.NoError(t, err)
+	check.Equal(t, []hostedRepo{{path: "public", url: "https://example.com/me/public.git"}}, list)
+
+	// As the authenticated user, the private repos are listed too
+	githubToken = "secret"
+	list, err = c.list(context.Background(), "me")
+	check.NoError(t, err)
+	check.Equal(t, []hostedRepo{
+		{path: "public", url: "https://example.com/me/public.git"},
+		{path: "private", url: "https://example.com/me/private.git"},
+	}, list)
+}
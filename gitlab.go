@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/richardwilkes/toolbox/cmdline"
+)
+
+type gitlabCmd struct {
+	dir             string
+	apiURL          string
+	useSSH          bool
+	includeArchived bool
+}
+
+type gitlabProject struct {
+	PathWithNamespace string `json:"path_with_namespace"`
+	HTTPURL           string `json:"http_url_to_repo"`
+	SSHURL            string `json:"ssh_url_to_repo"`
+	Archived          bool   `json:"archived"`
+}
+
+func (c *gitlabCmd) Name() string {
+	return "gitlab"
+}
+
+func (c *gitlabCmd) Usage() string {
+	return "Mirrors the projects of a GitLab group and its subgroups, cloning those that are missing " +
+		"and pulling the rest."
+}
+
+func (c *gitlabCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage() + " Subgroups are mirrored as subdirectories. The API token is taken from the " +
+		"gitlab_token configuration setting or, failing that, the GITLAB_TOKEN environment variable."
+	addCommonOptions(cl)
+	cl.UsageSuffix = "<group path>"
+	c.apiURL = "https://gitlab.com"
+	cl.NewGeneralOption(&c.dir).SetName("dir").SetArg("path").
+		SetUsage("The directory to mirror the projects into. Defaults to the current directory")
+	cl.NewGeneralOption(&c.apiURL).SetName("api-url").SetArg("url").
+		SetUsage("The base URL of the GitLab instance")
+	cl.NewGeneralOption(&c.useSSH).SetName("ssh").
+		SetUsage("Clone using SSH URLs rather than HTTPS")
+	cl.NewGeneralOption(&c.includeArchived).SetName("archived").
+		SetUsage("Include archived projects")
+	addDryRunOption(cl)
+	remaining := cl.Parse(args)
+	if len(remaining) != 1 {
+		cl.FatalMsg("A single group must be specified")
+	}
+	group := strings.Trim(remaining[0], "/")
+	cloneMissing = true
+	process([]string{c.dir}, hostedSource(c.dir, func(ctx context.Context) ([]hostedRepo, error) {
+		return c.list(ctx, group)
+	}), pullRepo)
+	return nil
+}
+
+// list returns the projects within group and its subgroups, with paths relative to the group.
+func (c *gitlabCmd) list(ctx context.Context, group string) ([]hostedRepo, error) {
+	headers := make(map[string]string)
+	if token := tokenOr(gitlabToken, "GITLAB_TOKEN"); token != "" {
+		headers["PRIVATE-TOKEN"] = token
+	}
+	u := fmt.Sprintf("%s/api/v4/groups/%s/projects?include_subgroups=true&per_page=100&order_by=path&sort=asc",
+		strings.TrimSuffix(c.apiURL, "/"), url.PathEscape(group))
+	if !c.includeArchived {
+		u += "&archived=false"
+	}
+	var projects []gitlabProject
+	if err := fetchAllPages(ctx, u, headers, decodePage(&projects)); err != nil {
+		return nil, err
+	}
+	list := make([]hostedRepo, 0, len(projects))
+	for _, one := range projects {
+		p := strings.TrimPrefix(one.PathWithNamespace, group+"/")
+		cloneURL := one.HTTPURL
+		if c.useSSH {
+			cloneURL = one.SSHURL
+		}
+		list = append(list, hostedRepo{path: p, url: cloneURL})
+	}
+	return list, nil
+}
@@ -0,0 +1,670 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/sideband"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+// useGoGit causes the common git operations to be performed in-process by go-git, rather than by running git.
+var useGoGit bool
+
+// gitAvailable returns true if the git binary can be found, and so can be fallen back upon.
+var gitAvailable = sync.OnceValue(func() bool {
+	_, err := exec.LookPath("git")
+	return err == nil
+})
+
+// commonGitPaths are the entries of a git directory shared by all of a repo's worktrees, and so found in the main
+// worktree's git directory. Those below them listed in perWorktreeGitPaths are the exceptions.
+var (
+	commonGitPaths = []string{
+		"branches", "config", "hooks", "info", "lfs", "logs", "modules", "objects", "packed-refs", "refs", "remotes",
+		"shallow", "worktrees",
+	}
+	perWorktreeGitPaths = []string{
+		"info/sparse-checkout", "logs/HEAD", "refs/bisect", "refs/rewritten", "refs/worktree",
+	}
+)
+
+// runGoGit performs the git command given by args with go-git, returning handled as false if it isn't one of the
+// commands that can be, or if go-git was unable to and git is available to try instead.
+func (r *repo) runGoGit(args []string) (out string, handled bool, err error) {
+	if len(args) == 0 || r.missing {
+		return "", false, nil
+	}
+	if r.goGitRepo == nil {
+		opts := &git.PlainOpenOptions{EnableDotGitCommonDir: true}
+		if r.goGitRepo, err = git.PlainOpenWithOptions(r.path, opts); err != nil {
+			r.goGitRepo = nil
+			return "", false, nil
+		}
+	}
+	ctx := r.ctx
+	if *r.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(r.ctx, *r.cfg.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	if out, handled, err = r.goGitCommand(ctx, args); !handled {
+		return "", false, nil
+	}
+	cmd := "go-git " + strings.Join(args, " ")
+	status := "ok"
+	if err != nil {
+		if ctx.Err() != nil && r.ctx.Err() == nil {
+			err = errs.NewWithCause(fmt.Sprintf("%s timed out after %v", cmd, *r.cfg.Timeout), ctx.Err())
+		} else {
+			err = errs.NewWithCause(cmd, err)
+		}
+		status = multirepo.ErrorText(err)
+	}
+	writeLogEntry(cmd, r.path, start, status, out)
+	if tui || verbose {
+		r.record(cmd, out, err)
+	}
+	return out, true, err
+}
+
+func (r *repo) goGitCommand(ctx context.Context, args []string) (out string, handled bool, err error) {
+	switch {
+	case slices.Equal(args, []string{"branch", "--show-current"}):
+		out, err = r.goGitCurrentBranch()
+		return out, true, err
+	case slices.Equal(args, []string{"status", "--porcelain=v2"}):
+		out, err = r.goGitStatus()
+		return out, true, err
+	case slices.Equal(args, []string{"stash", "list", "--format=%ct"}):
+		out, err = r.goGitStashTimes()
+		return out, true, err
+	case args[0] == "rev-parse":
+		return r.goGitRevParse(args[1:])
+	case len(args) == 4 && args[0] == "rev-list" && args[1] == "--left-right" && args[2] == "--count":
+		left, right, ok := strings.Cut(args[3], "...")
+		if !ok {
+			return "", false, nil
+		}
+		out, err = r.goGitCountLeftRight(left, right)
+		return out, true, err
+	case (args[0] == "fetch" || args[0] == "pull") && len(r.rewrite) != 0:
+		// go-git would use the origin URL as configured
+		return "", false, nil
+	case args[0] == "fetch":
+		return r.goGitFetch(ctx, args[1:])
+	case args[0] == "pull":
+		return r.goGitPull(ctx, args[1:])
+	case slices.Equal(args, []string{"remote"}):
+		out, err = r.goGitRemotes()
+		return out, true, err
+	case len(args) == 3 && args[0] == "remote" && args[1] == "get-url":
+		cfg, cfgErr := r.goGitRepo.Config()
+		if cfgErr != nil {
+			return "", true, cfgErr
+		}
+		if remote, ok := cfg.Remotes[args[2]]; ok && len(remote.URLs) != 0 {
+			return remote.URLs[0], true, nil
+		}
+		return "error: No such remote '" + args[2] + "'", true, errs.New("no such remote")
+	default:
+		return "", false, nil
+	}
+}
+
+// goGitDirs returns the repo's git directory and the common directory it shares with the repo's other worktrees, which
+// is the same for the main worktree.
+func (r *repo) goGitDirs() (gitDir, commonDir string) {
+	gitDir = filepath.Join(r.path, ".git")
+	if fi, err := os.Stat(gitDir); err == nil && !fi.IsDir() {
+		gitDir = multirepo.ResolveGitDir(gitDir)
+	}
+	commonDir = gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		if commonDir = strings.TrimSpace(string(data)); !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+		commonDir = filepath.Clean(commonDir)
+	}
+	return gitDir, commonDir
+}
+
+// goGitPath returns the location of name within the git directory, as "git rev-parse --git-path" does.
+func (r *repo) goGitPath(name string) string {
+	gitDir, commonDir := r.goGitDirs()
+	first, _, _ := strings.Cut(name, "/")
+	if slices.Contains(commonGitPaths, first) && !slices.ContainsFunc(perWorktreeGitPaths, func(p string) bool {
+		return name == p || strings.HasPrefix(name, p+"/")
+	}) {
+		return filepath.Join(commonDir, name)
+	}
+	return filepath.Join(gitDir, name)
+}
+
+func (r *repo) goGitCurrentBranch() (string, error) {
+	ref, err := r.goGitRepo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return "", err
+	}
+	if ref.Type() == plumbing.SymbolicReference && ref.Target().IsBranch() {
+		return ref.Target().Short(), nil
+	}
+	return "", nil
+}
+
+// goGitStatus returns the local changes in the form output by "git status --porcelain=v2", though with only the
+// leading fields and the path.
+func (r *repo) goGitStatus() (string, error) {
+	w, err := r.goGitRepo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	var status git.Status
+	if status, err = w.Status(); err != nil {
+		return "", err
+	}
+	paths := make([]string, 0, len(status))
+	for p := range status {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+	code := func(c git.StatusCode) byte {
+		if c == git.Unmodified {
+			return '.'
+		}
+		return byte(c)
+	}
+	var lines []string
+	for _, p := range paths {
+		fs := status[p]
+		switch {
+		case fs.Staging == git.Untracked || fs.Worktree == git.Untracked:
+			lines = append(lines, "? "+p)
+		case fs.Staging == git.UpdatedButUnmerged || fs.Worktree == git.UpdatedButUnmerged:
+			lines = append(lines, "u UU "+p)
+		case fs.Staging != git.Unmodified || fs.Worktree != git.Unmodified:
+			lines = append(lines, fmt.Sprintf("1 %c%c %s", code(fs.Staging), code(fs.Worktree), p))
+		default:
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// goGitStashTimes returns the times the stashes were made, newest first, as seconds since the epoch, one per line.
+func (r *repo) goGitStashTimes() (string, error) {
+	data, err := os.ReadFile(r.goGitPath("logs/refs/stash"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	var times []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// Each entry is "<old> <new> <name> <<email>> <seconds> <zone>\t<message>"
+		line, _, _ = strings.Cut(line, "\t")
+		if i := strings.LastIndexByte(line, '>'); i != -1 {
+			if fields := strings.Fields(line[i+1:]); len(fields) != 0 {
+				times = append(times, fields[0])
+			}
+		}
+	}
+	slices.Reverse(times)
+	return strings.Join(times, "\n"), nil
+}
+
+func (r *repo) goGitRevParse(args []string) (out string, handled bool, err error) {
+	switch {
+	case slices.Equal(args, []string{"--abbrev-ref", "@{upstream}"}):
+		var name plumbing.ReferenceName
+		if name, err = r.goGitUpstream(); err != nil {
+			return "fatal: no upstream configured for branch", true, err
+		}
+		return name.Short(), true, nil
+	case slices.Equal(args, []string{"--is-shallow-repository"}):
+		var shallow []plumbing.Hash
+		if shallow, err = r.goGitRepo.Storer.Shallow(); err != nil {
+			return "", true, err
+		}
+		if len(shallow) != 0 {
+			return "true", true, nil
+		}
+		return "false", true, nil
+	case slices.Equal(args, []string{"--absolute-git-dir"}):
+		gitDir, _ := r.goGitDirs()
+		return gitDir, true, nil
+	case len(args) != 0 && len(args)%2 == 0 && args[0] == "--git-path":
+		paths := make([]string, 0, len(args)/2)
+		for i := 0; i < len(args); i += 2 {
+			if args[i] != "--git-path" {
+				return "", false, nil
+			}
+			paths = append(paths, r.goGitPath(args[i+1]))
+		}
+		return strings.Join(paths, "\n"), true, nil
+	default:
+	}
+	var rev string
+	for _, arg := range args {
+		switch {
+		case arg == "-q" || arg == "--quiet" || arg == "--verify":
+		case strings.HasPrefix(arg, "-") || rev != "":
+			return "", false, nil
+		default:
+			rev = arg
+		}
+	}
+	if rev == "" {
+		return "", false, nil
+	}
+	var hash plumbing.Hash
+	if hash, err = r.goGitResolve(rev); err != nil {
+		return "", true, err
+	}
+	return hash.String(), true, nil
+}
+
+// goGitUpstream returns the name of the remote-tracking ref that is the current branch's upstream.
+func (r *repo) goGitUpstream() (plumbing.ReferenceName, error) {
+	branch, err := r.goGitCurrentBranch()
+	if err != nil {
+		return "", err
+	}
+	var cfg *gitconfig.Config
+	if cfg, err = r.goGitRepo.Config(); err != nil {
+		return "", err
+	}
+	b, ok := cfg.Branches[branch]
+	if !ok || b.Remote == "" || b.Merge == "" {
+		return "", errs.New("no upstream configured")
+	}
+	if b.Remote == "." {
+		return b.Merge, nil
+	}
+	if remote, exists := cfg.Remotes[b.Remote]; exists {
+		for _, spec := range remote.Fetch {
+			if spec.Match(b.Merge) {
+				return spec.Dst(b.Merge), nil
+			}
+		}
+	}
+	return "", errs.New("upstream is not fetched")
+}
+
+func (r *repo) goGitResolve(rev string) (plumbing.Hash, error) {
+	switch {
+	case rev == "@{upstream}" || rev == "@{u}":
+		name, err := r.goGitUpstream()
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		return r.goGitResolve(name.String())
+	case strings.HasPrefix(rev, "refs/") || rev == "HEAD":
+		ref, err := r.goGitRepo.Reference(plumbing.ReferenceName(rev), true)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		return ref.Hash(), nil
+	default:
+		hash, err := r.goGitRepo.ResolveRevision(plumbing.Revision(rev))
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		return *hash, nil
+	}
+}
+
+// goGitCountLeftRight returns the number of commits reachable only from left and only from right, separated by a tab,
+// as "git rev-list --left-right --count left...right" does.
+func (r *repo) goGitCountLeftRight(left, right string) (string, error) {
+	leftHash, err := r.goGitResolve(left)
+	if err != nil {
+		return "", err
+	}
+	var rightHash plumbing.Hash
+	if rightHash, err = r.goGitResolve(right); err != nil {
+		return "", err
+	}
+	var leftOnly, rightOnly int
+	if leftOnly, rightOnly, err = goGitPaint(r.goGitRepo, leftHash, rightHash, nil); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d\t%d", leftOnly, rightOnly), nil
+}
+
+const (
+	paintLeft  = 1
+	paintRight = 2
+	paintBoth  = paintLeft | paintRight
+)
+
+// commitQueue orders commits with the most recently committed first.
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int           { return len(q) }
+func (q commitQueue) Less(i, j int) bool { return q[i].Committer.When.After(q[j].Committer.When) }
+func (q commitQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x any)        { *q = append(*q, x.(*object.Commit)) }
+func (q *commitQueue) Pop() any {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// goGitPaint counts the commits reachable from only one of left and right, calling onRightOnly, if given, with each of
+// those reachable only from right. Commits are visited newest first, marking their parents as reachable from the same
+// side, so that by the time a commit is visited, all of its descendants have been and which sides it can be reached
+// from is known. The walk stops once only commits reachable from both remain. Like git, this relies upon commit times
+// being consistent with the history.
+func goGitPaint(gitRepo *git.Repository, left, right plumbing.Hash,
+	onRightOnly func(c *object.Commit)) (leftOnly, rightOnly int, err error) {
+	if left == right {
+		return 0, 0, nil
+	}
+	flags := make(map[plumbing.Hash]int)
+	queued := make(map[plumbing.Hash]bool)
+	var queue commitQueue
+	var pending int // the number of queued commits not reachable from both sides
+	add := func(hash plumbing.Hash, flag int) error {
+		old, seen := flags[hash]
+		flags[hash] = old | flag
+		switch {
+		case !seen:
+			c, objErr := gitRepo.CommitObject(hash)
+			if objErr != nil {
+				if errors.Is(objErr, plumbing.ErrObjectNotFound) {
+					// The history of shallow clones ends early
+					return nil
+				}
+				return objErr
+			}
+			heap.Push(&queue, c)
+			queued[hash] = true
+			if flag != paintBoth {
+				pending++
+			}
+		case queued[hash] && old != paintBoth && old|flag == paintBoth:
+			pending--
+		default:
+		}
+		return nil
+	}
+	if err = add(left, paintLeft); err != nil {
+		return 0, 0, err
+	}
+	if err = add(right, paintRight); err != nil {
+		return 0, 0, err
+	}
+	for pending > 0 {
+		c, ok := heap.Pop(&queue).(*object.Commit)
+		if !ok {
+			break
+		}
+		delete(queued, c.Hash)
+		flag := flags[c.Hash]
+		switch flag {
+		case paintLeft:
+			leftOnly++
+			pending--
+		case paintRight:
+			rightOnly++
+			pending--
+			if onRightOnly != nil {
+				onRightOnly(c)
+			}
+		default:
+		}
+		for _, parent := range c.ParentHashes {
+			if err = add(parent, flag); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	return leftOnly, rightOnly, nil
+}
+
+// goGitLog returns a line for each of the commits reachable from to but not from, in the repo at dir, giving its
+// abbreviated hash and subject, newest first, as "git log --format='%h %s' from..to" does.
+func goGitLog(dir, from, to string) ([]string, error) {
+	gitRepo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	var fromHash, toHash *plumbing.Hash
+	if fromHash, err = gitRepo.ResolveRevision(plumbing.Revision(from)); err != nil {
+		return nil, errs.Wrap(err)
+	}
+	if toHash, err = gitRepo.ResolveRevision(plumbing.Revision(to)); err != nil {
+		return nil, errs.Wrap(err)
+	}
+	var lines []string
+	if _, _, err = goGitPaint(gitRepo, *fromHash, *toHash, func(c *object.Commit) {
+		subject, _, _ := strings.Cut(strings.TrimSpace(c.Message), "\n")
+		lines = append(lines, shortHash(c.Hash.String())+" "+subject)
+	}); err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return lines, nil
+}
+
+// goGitOriginURL returns the URL of the origin remote for the repo at dir as go-git sees it, or an empty string if
+// there isn't one.
+func goGitOriginURL(dir string) string {
+	gitRepo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return ""
+	}
+	var cfg *gitconfig.Config
+	if cfg, err = gitRepo.Config(); err != nil {
+		return ""
+	}
+	if remote, ok := cfg.Remotes["origin"]; ok && len(remote.URLs) != 0 {
+		return remote.URLs[0]
+	}
+	return ""
+}
+
+// goGitFallBack returns true if err from go-git indicates something git may be able to do instead, such as obtain
+// credentials from a helper or use the user's ssh configuration.
+func goGitFallBack(err error) bool {
+	if !gitAvailable() {
+		return false
+	}
+	if errors.Is(err, transport.ErrAuthenticationRequired) || errors.Is(err, transport.ErrAuthorizationFailed) ||
+		errors.Is(err, transport.ErrInvalidAuthMethod) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "ssh:") || strings.Contains(msg, "knownhosts") || strings.Contains(msg, "unsupported")
+}
+
+func (r *repo) goGitFetch(ctx context.Context, args []string) (out string, handled bool, err error) {
+	opts := git.FetchOptions{Tags: git.TagFollowing}
+	var all bool
+	var remotes []string
+	for _, arg := range args {
+		switch arg {
+		case "--all":
+			all = true
+		case "--prune":
+			opts.Prune = true
+		case "--tags":
+			opts.Tags = git.AllTags
+		case "--force":
+			opts.Force = true
+		case "--progress":
+			opts.Progress = multirepo.NewProgressWriter(r.showProgress)
+		default:
+			if strings.HasPrefix(arg, "-") || len(remotes) != 0 {
+				return "", false, nil
+			}
+			remotes = append(remotes, arg)
+		}
+	}
+	switch {
+	case all:
+		if remotes, err = r.goGitRemoteNames(); err != nil {
+			return "", true, err
+		}
+	case len(remotes) == 0:
+		remotes = []string{git.DefaultRemoteName}
+		var cfg *gitconfig.Config
+		var branch string
+		if cfg, err = r.goGitRepo.Config(); err == nil {
+			if branch, err = r.goGitCurrentBranch(); err == nil {
+				if b, ok := cfg.Branches[branch]; ok && b.Remote != "" && b.Remote != "." {
+					remotes = []string{b.Remote}
+				}
+			}
+		}
+	default:
+	}
+	for _, name := range remotes {
+		opts.RemoteName = name
+		if err = r.goGitRepo.FetchContext(ctx, &opts); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			if goGitFallBack(err) {
+				return "", false, nil
+			}
+			return "fatal: " + err.Error(), true, err
+		}
+	}
+	return "", true, nil
+}
+
+// goGitPull fast-forwards the current branch to its upstream. A pull that can't be fast-forwarded is left to git to
+// merge, unless only fast-forwarding was requested.
+func (r *repo) goGitPull(ctx context.Context, args []string) (out string, handled bool, err error) {
+	ffOnly := false
+	var progress sideband.Progress
+	for _, arg := range args {
+		switch arg {
+		case "--ff-only":
+			ffOnly = true
+		case "--progress":
+			progress = multirepo.NewProgressWriter(r.showProgress)
+		default:
+			return "", false, nil
+		}
+	}
+	branch, err := r.goGitCurrentBranch()
+	if err != nil || branch == "" {
+		return "", false, nil
+	}
+	var cfg *gitconfig.Config
+	if cfg, err = r.goGitRepo.Config(); err != nil {
+		return "", true, err
+	}
+	b, ok := cfg.Branches[branch]
+	if !ok || b.Remote == "" || b.Remote == "." || b.Merge == "" {
+		return "", false, nil
+	}
+	var w *git.Worktree
+	if w, err = r.goGitRepo.Worktree(); err != nil {
+		return "", true, err
+	}
+	var before *plumbing.Reference
+	if before, err = r.goGitRepo.Head(); err != nil {
+		return "", false, nil
+	}
+	err = w.PullContext(ctx, &git.PullOptions{RemoteName: b.Remote, ReferenceName: b.Merge, Progress: progress})
+	switch {
+	case err == nil:
+	case errors.Is(err, git.NoErrAlreadyUpToDate):
+		return "Already up to date.", true, nil
+	case errors.Is(err, git.ErrNonFastForwardUpdate):
+		// go-git also reports this when the branch is ahead of its upstream
+		var counts string
+		if counts, err = r.goGitCountLeftRight("HEAD", "@{upstream}"); err == nil && strings.HasSuffix(counts, "\t0") {
+			return "Already up to date.", true, nil
+		}
+		if !ffOnly {
+			return "", false, nil
+		}
+		return "fatal: Not possible to fast-forward, aborting.", true, git.ErrNonFastForwardUpdate
+	case goGitFallBack(err):
+		return "", false, nil
+	default:
+		return "fatal: " + err.Error(), true, err
+	}
+	var after *plumbing.Reference
+	if after, err = r.goGitRepo.Head(); err != nil {
+		return "", true, err
+	}
+	out = fmt.Sprintf("Updating %s..%s\nFast-forward", before.Hash().String()[:7], after.Hash().String()[:7])
+	if summary := r.goGitDiffSummary(ctx, before.Hash(), after.Hash()); summary != "" {
+		out += "\n" + summary
+	}
+	return out, true, nil
+}
+
+// goGitDiffSummary returns the summary line of the diffstat between two commits in the form git uses, or an empty
+// string if no files changed or the diff couldn't be determined.
+func (r *repo) goGitDiffSummary(ctx context.Context, from, to plumbing.Hash) string {
+	fromCommit, err := r.goGitRepo.CommitObject(from)
+	if err != nil {
+		return ""
+	}
+	var toCommit *object.Commit
+	if toCommit, err = r.goGitRepo.CommitObject(to); err != nil {
+		return ""
+	}
+	var patch *object.Patch
+	if patch, err = fromCommit.PatchContext(ctx, toCommit); err != nil {
+		return ""
+	}
+	stats := patch.Stats()
+	if len(stats) == 0 {
+		return ""
+	}
+	var insertions, deletions int
+	for _, stat := range stats {
+		insertions += stat.Addition
+		deletions += stat.Deletion
+	}
+	summary := fmt.Sprintf(" %d %s changed", len(stats), plural(len(stats), "file", "files"))
+	if insertions != 0 || deletions == 0 {
+		summary += fmt.Sprintf(", %d %s(+)", insertions, plural(insertions, "insertion", "insertions"))
+	}
+	if deletions != 0 || insertions == 0 {
+		summary += fmt.Sprintf(", %d %s(-)", deletions, plural(deletions, "deletion", "deletions"))
+	}
+	return summary
+}
+
+func (r *repo) goGitRemoteNames() ([]string, error) {
+	cfg, err := r.goGitRepo.Config()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(cfg.Remotes))
+	for name := range cfg.Remotes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+func (r *repo) goGitRemotes() (string, error) {
+	names, err := r.goGitRemoteNames()
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(names, "\n"), nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/richardwilkes/toolbox/check"
+)
+
+func TestGoGit(t *testing.T) {
+	if !gitAvailable() {
+		t.Skip("git is needed to build the history and provide the expected results")
+	}
+	dir := t.TempDir()
+	var tick int
+	run := func(args ...string) string {
+		t.Helper()
+		// Commit times must be distinct and consistent with the history, as git's own walk relies upon that too
+		tick++
+		date := fmt.Sprintf("%d +0000", 1700000000+tick*60)
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=gp", "GIT_AUTHOR_EMAIL=gp@example.com",
+			"GIT_COMMITTER_NAME=gp", "GIT_COMMITTER_EMAIL=gp@example.com", "GIT_AUTHOR_DATE="+date,
+			"GIT_COMMITTER_DATE="+date, "GIT_CONFIG_GLOBAL="+os.DevNull, "GIT_CONFIG_NOSYSTEM=1")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	commit := func(msg string) {
+		run("commit", "--allow-empty", "-q", "-m", msg)
+	}
+	run("init", "-q", "-b", "main")
+	commit("c1")
+	commit("c2")
+	run("tag", "base")
+	run("checkout", "-q", "-b", "side")
+	commit("s1")
+	commit("s2")
+	run("checkout", "-q", "main")
+	commit("c3")
+	run("checkout", "-q", "side")
+	run("merge", "-q", "--no-edit", "main")
+	commit("s3")
+	run("checkout", "-q", "main")
+	commit("c4")
+	commit("c5")
+	// Criss-cross merges, so that the two branches share more than one merge base
+	run("checkout", "-q", "-b", "cross", "main")
+	run("merge", "-q", "--no-edit", "side")
+	run("checkout", "-q", "side")
+	run("merge", "-q", "--no-edit", "main")
+	commit("s4")
+	run("checkout", "-q", "cross")
+	commit("x1")
+	run("checkout", "-q", "main")
+
+	gitRepo, err := git.PlainOpen(dir)
+	check.NoError(t, err)
+	r := &repo{location: &location{}, path: dir, goGitRepo: gitRepo}
+	for _, pair := range [][2]string{
+		{"main", "side"},
+		{"side", "main"},
+		{"main", "main"},
+		{"base", "side"},
+		{"side", "base"},
+		{"cross", "side"},
+		{"side", "cross"},
+		{"main", "cross"},
+		{"refs/heads/side", "HEAD"},
+	} {
+		spec := pair[0] + "..." + pair[1]
+		var out string
+		out, err = r.goGitCountLeftRight(pair[0], pair[1])
+		check.NoError(t, err, spec)
+		check.Equal(t, run("rev-list", "--left-right", "--count", spec), out, spec)
+	}
+	_, err = r.goGitCountLeftRight("main", "missing")
+	check.Error(t, err)
+
+	for _, spec := range []string{"main..side", "side..main", "base..cross", "cross..side", "main..main"} {
+		from, to, _ := strings.Cut(spec, "..")
+		var lines []string
+		lines, err = goGitLog(dir, from, to)
+		check.NoError(t, err, spec)
+		check.Equal(t, run("log", "--format=%h %s", spec), strings.Join(lines, "\n"), spec)
+	}
+
+	saved := useGoGit
+	defer func() { useGoGit = saved }()
+	for _, useGoGit = range []bool{false, true} {
+		check.Equal(t, "", originURL(dir), "go-git: %v", useGoGit)
+	}
+	run("remote", "add", "origin", "git@github.com:org/repo.git")
+	for _, useGoGit = range []bool{false, true} {
+		check.Equal(t, "git@github.com:org/repo.git", originURL(dir), "go-git: %v", useGoGit)
+	}
+}
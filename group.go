@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+var group bool
+
+// rowInfo describes a single row of the display.
+type rowInfo struct {
+	path    string // the repo's path, or empty for a group header
+	label   string
+	col     int // where the repo's branch and status start
+	heading int // the row of the repo's group header, if grouped
+}
+
+// layoutRows returns the rows with which to display the repos in list. With --group, the repos are grouped by their
+// parent directory, each group being preceded by a header and having its repos aligned independently of the others.
+func layoutRows(list []string, root string) []*rowInfo {
+	if !group {
+		return labelRows(list, func(p string) string { return displayName(root, p) }, 0)
+	}
+	var dirs []string
+	members := make(map[string][]string)
+	for _, p := range list {
+		dir := filepath.Dir(p)
+		if _, exists := members[dir]; !exists {
+			dirs = append(dirs, dir)
+		}
+		members[dir] = append(members[dir], p)
+	}
+	rows := make([]*rowInfo, 0, len(list)+len(dirs))
+	for _, dir := range dirs {
+		rows = append(rows, &rowInfo{label: dir + ":"})
+		rows = append(rows, labelRows(members[dir], filepath.Base, len(rows))...)
+	}
+	return rows
+}
+
+// labelRows returns the rows for the repos in list, labeled with the names returned by name and aligned with each
+// other. If heading is not zero, it is the row of the header the repos appear beneath, and their labels are indented.
+func labelRows(list []string, name func(p string) string, heading int) []*rowInfo {
+	indent := 0
+	if heading != 0 {
+		indent = 2
+	}
+	longest := 0
+	for _, p := range list {
+		longest = max(longest, len(name(p)))
+	}
+	format := fmt.Sprintf("%%%ds:", longest+indent)
+	col := longest + indent + 3
+	if icons {
+		// Leave room for the icon and drop the colon
+		format = fmt.Sprintf("%%%ds", longest+indent)
+		col++
+	}
+	rows := make([]*rowInfo, len(list))
+	for i, p := range list {
+		rows[i] = &rowInfo{path: p, label: fmt.Sprintf(format, name(p)), col: col, heading: heading}
+	}
+	return rows
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/cmdline"
+	"github.com/richardwilkes/toolbox/errs"
+	"github.com/richardwilkes/toolbox/xio"
+)
+
+// maxHistoryRuns limits how many runs the history retains.
+const maxHistoryRuns = 500
+
+var (
+	// commandName is the name of the command being run, as recorded in the history.
+	commandName string
+	// recording is cleared by the commands whose runs aren't recorded in the history, those that only inspect the
+	// repos.
+	recording = true
+)
+
+// historyRun is the record of a single run kept in the history.
+type historyRun struct {
+	Time     time.Time     `json:"time"`
+	Command  string        `json:"command"`
+	Duration float64       `json:"duration"` // in seconds
+	Repos    []historyRepo `json:"repos"`
+}
+
+// historyRepo is the record of a single repo within a run.
+type historyRepo struct {
+	Path    string            `json:"path"`
+	Outcome multirepo.Outcome `json:"outcome"`
+	Status  string            `json:"status,omitempty"`
+	Head    string            `json:"head,omitempty"`
+}
+
+// historyChange describes a repo whose HEAD moved during a run.
+type historyChange struct {
+	path string
+	from string
+	to   string
+}
+
+type historyCmd struct {
+	last bool
+}
+
+func (c *historyCmd) Name() string {
+	if c.last {
+		return "last"
+	}
+	return "history"
+}
+
+func (c *historyCmd) Usage() string {
+	if c.last {
+		return "Shows what changed in the most recent run that updated repos: the repos whose HEAD moved, with the " +
+			"commits that brought in, and those that failed."
+	}
+	return "Lists past runs of the commands that update repos, with their outcomes and the repos whose " +
+		"HEAD moved in each."
+}
+
+func (c *historyCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	cl.UsageSuffix = ""
+	var since time.Duration
+	limit := 20
+	if !c.last {
+		cl.NewGeneralOption(&since).SetName("since").SetArg("duration").
+			SetUsage("Only list the runs made within this long, such as 24h. Zero means there is no such limit")
+		cl.NewGeneralOption(&limit).SetName("limit").SetArg("N").
+			SetUsage("The maximum number of runs to list, most recent last. Zero means there is no limit")
+	}
+	if len(cl.Parse(args)) != 0 {
+		cl.FatalMsg("No arguments are accepted")
+	}
+	runs, err := loadHistory()
+	if err != nil {
+		cl.FatalMsg(multirepo.ErrorText(err))
+	}
+	changes := historyChanges(runs)
+	if c.last {
+		printLastRun(runs, changes)
+		return nil
+	}
+	first := 0
+	if since > 0 {
+		cutoff := time.Now().Add(-since)
+		for first < len(runs) && runs[first].Time.Before(cutoff) {
+			first++
+		}
+	}
+	if limit > 0 {
+		first = max(first, len(runs)-limit)
+	}
+	if first == len(runs) {
+		fmt.Println("No runs have been recorded")
+		return nil
+	}
+	for i := first; i < len(runs); i++ {
+		fmt.Println(describeRun(&runs[i]))
+		for _, change := range changes[i] {
+			fmt.Printf("    %s: %s..%s\n", change.path, shortHash(change.from), shortHash(change.to))
+		}
+	}
+	return nil
+}
+
+// describeRun returns a line describing when the run was made and its outcomes.
+func describeRun(run *historyRun) string {
+	counts := make(map[multirepo.Outcome]int)
+	for _, r := range run.Repos {
+		counts[r.Outcome]++
+	}
+	var parts []string
+	for _, o := range summaryOutcomes {
+		if counts[o] != 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[o], o))
+		}
+	}
+	elapsed := time.Duration(run.Duration * float64(time.Second)).Round(100 * time.Millisecond)
+	line := fmt.Sprintf("%s %s: %d %s in %s", run.Time.Local().Format(time.DateTime), run.Command, len(run.Repos),
+		plural(len(run.Repos), "repo", "repos"), elapsed)
+	if len(parts) != 0 {
+		line += ": " + strings.Join(parts, ", ")
+	}
+	return line
+}
+
+// printLastRun prints the repos whose HEAD moved during the most recent run that moved any, along with the commits
+// that brought in, followed by those that failed in the most recent run.
+func printLastRun(runs []historyRun, changes [][]historyChange) {
+	if len(runs) == 0 {
+		fmt.Println("No runs have been recorded")
+		return
+	}
+	i := len(runs) - 1
+	for i > 0 && len(changes[i]) == 0 {
+		i--
+	}
+	if len(changes[i]) == 0 {
+		fmt.Println("No repos have changed in the recorded runs")
+	} else {
+		fmt.Println(describeRun(&runs[i]))
+		for _, change := range changes[i] {
+			fmt.Printf("\n%s: %s..%s\n", change.path, shortHash(change.from), shortHash(change.to))
+			// The commits can only be listed while the repo still has them
+			lines, err := commitsBetween(change.path, change.from, change.to)
+			if err != nil {
+				continue
+			}
+			for _, line := range lines {
+				fmt.Println("  " + line)
+			}
+		}
+	}
+	var failed []string
+	for _, r := range runs[len(runs)-1].Repos {
+		switch r.Outcome {
+		case multirepo.Failed, multirepo.Aborted, multirepo.TimedOut:
+			failed = append(failed, r.Path+": "+r.Status)
+		default:
+		}
+	}
+	if len(failed) != 0 {
+		fmt.Printf("\nFailed in the most recent run, at %s:\n", runs[len(runs)-1].Time.Local().Format(time.DateTime))
+		for _, one := range failed {
+			fmt.Println("  " + one)
+		}
+	}
+}
+
+// historyChanges returns, for each run, the repos whose HEAD differs from that recorded for them by an earlier run.
+func historyChanges(runs []historyRun) [][]historyChange {
+	heads := make(map[string]string)
+	changes := make([][]historyChange, len(runs))
+	for i := range runs {
+		for _, r := range runs[i].Repos {
+			if r.Head == "" {
+				continue
+			}
+			if prev, ok := heads[r.Path]; ok && prev != r.Head {
+				changes[i] = append(changes[i], historyChange{path: r.Path, from: prev, to: r.Head})
+			}
+			heads[r.Path] = r.Head
+		}
+	}
+	return changes
+}
+
+// commitsBetween returns a line for each of the commits reachable from to but not from in the repo at dir, giving its
+// abbreviated hash and subject, newest first. go-git is used where git isn't installed.
+func commitsBetween(dir, from, to string) ([]string, error) {
+	if !gitAvailable() {
+		return goGitLog(dir, from, to)
+	}
+	out, err := exec.Command("git", "-C", dir, "log", "--format=%h %s", from+".."+to).Output()
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+func shortHash(hash string) string {
+	return hash[:min(len(hash), 7)]
+}
+
+// recordingHistory returns true if the run is to be recorded in the history: runs of the commands that only inspect
+// the repos, and dry runs, aren't.
+func recordingHistory() bool {
+	return recording && !dryRun
+}
+
+// recordHead records the commit the repo has checked out, for the history.
+func (r *repo) recordHead() {
+	if head, err := r.gitActual("rev-parse", "HEAD"); err == nil {
+		r.result.Head = head
+	}
+}
+
+// historyPath returns the path to the file holding the history, which lives alongside the user's configuration file.
+func historyPath() string {
+	return filepath.Join(filepath.Dir(userConfigPath()), "history.jsonl")
+}
+
+// loadHistory returns the recorded runs, oldest first. Lines that can't be read, such as one cut short by a run that
+// was interrupted while recording it, are skipped.
+func loadHistory() ([]historyRun, error) {
+	f, err := os.Open(historyPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, errs.Wrap(err)
+	}
+	defer xio.CloseIgnoringErrors(f)
+	var runs []historyRun
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		var run historyRun
+		if json.Unmarshal(scanner.Bytes(), &run) == nil {
+			runs = append(runs, run)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return runs, nil
+}
+
+// saveHistory appends the run to the history, dropping the oldest runs once there are more than maxHistoryRuns.
+func saveHistory(repos []*repo, start time.Time) error {
+	run := historyRun{
+		Time:     start,
+		Command:  commandName,
+		Duration: time.Since(start).Seconds(),
+		Repos:    make([]historyRepo, len(repos)),
+	}
+	for i, r := range repos {
+		run.Repos[i] = historyRepo{
+			Path:    r.path,
+			Outcome: r.result.Outcome,
+			Status:  r.result.Status,
+			Head:    r.result.Head,
+		}
+	}
+	data, err := json.Marshal(&run)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	path := historyPath()
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errs.NewWithCause("unable to create "+filepath.Dir(path), err)
+	}
+	var f *os.File
+	if f, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644); err != nil {
+		return errs.NewWithCause("unable to open the history "+path, err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errs.NewWithCause("unable to record the run in the history "+path, err)
+	}
+	return trimHistory(path)
+}
+
+// trimHistory drops the oldest runs from the history at path, should it hold more than maxHistoryRuns.
+func trimHistory(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= maxHistoryRuns {
+		return nil
+	}
+	tmp := path + ".tmp" + strconv.Itoa(os.Getpid())
+	if err = os.WriteFile(tmp, []byte(strings.Join(lines[len(lines)-maxHistoryRuns:], "")), 0o644); err != nil {
+		return errs.NewWithCause("unable to trim the history "+path, err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return errs.NewWithCause("unable to trim the history "+path, err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/check"
+)
+
+func TestHistoryChanges(t *testing.T) {
+	runs := []historyRun{
+		{Repos: []historyRepo{
+			{Path: "/src/a", Outcome: multirepo.Unchanged, Head: "a1"},
+			{Path: "/src/b", Outcome: multirepo.Unchanged, Head: "b1"},
+			{Path: "/src/c", Outcome: multirepo.Failed},
+		}},
+		{Repos: []historyRepo{
+			{Path: "/src/a", Outcome: multirepo.Updated, Head: "a2"},
+			{Path: "/src/b", Outcome: multirepo.Unchanged, Head: "b1"},
+			// Repos without a recorded HEAD, such as those that failed, neither change nor forget their last HEAD
+			{Path: "/src/c", Outcome: multirepo.Unchanged, Head: "c1"},
+		}},
+		{Repos: []historyRepo{
+			{Path: "/src/b", Outcome: multirepo.Failed},
+		}},
+		{Repos: []historyRepo{
+			{Path: "/src/a", Outcome: multirepo.Unchanged, Head: "a2"},
+			{Path: "/src/b", Outcome: multirepo.Updated, Head: "b2"},
+			{Path: "/src/c", Outcome: multirepo.Updated, Head: "c2"},
+			{Path: "/src/d", Outcome: multirepo.Updated, Head: "d1"},
+		}},
+	}
+	check.Equal(t, [][]historyChange{
+		nil,
+		{{path: "/src/a", from: "a1", to: "a2"}},
+		nil,
+		{{path: "/src/b", from: "b1", to: "b2"}, {path: "/src/c", from: "c1", to: "c2"}},
+	}, historyChanges(runs))
+	check.Equal(t, [][]historyChange{}, historyChanges(nil))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// postPullHook returns the command to run in the repo after a pull has brought in changes, or an empty string if there
+// is none. A command given on the command line takes precedence. Otherwise, the first of the post_pull_repos globs, in
+// sorted order, that matches the repo's directory name or relative path is used, falling back to post_pull.
+func (r *repo) postPullHook() string {
+	if overrides.PostPull != "" {
+		return overrides.PostPull
+	}
+	patterns := make([]string, 0, len(r.cfg.PostPullRepos))
+	for pattern := range r.cfg.PostPullRepos {
+		patterns = append(patterns, pattern)
+	}
+	slices.Sort(patterns)
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, filepath.Base(r.path)); ok {
+			return r.cfg.PostPullRepos[pattern]
+		}
+		if ok, _ := filepath.Match(pattern, r.rel); ok {
+			return r.cfg.PostPullRepos[pattern]
+		}
+	}
+	return r.cfg.PostPull
+}
+
+// runPostPull runs the post-pull hook, if there is one, folding its result into the repo's status.
+func (r *repo) runPostPull() {
+	command := r.postPullHook()
+	if command == "" {
+		return
+	}
+	name, args := shellCommand(r.expandTemplate(command, shellQuote))
+	if _, err := r.run(name, args...); err != nil {
+		r.fail(r.result.Status+"; post-pull hook failed", err)
+		return
+	}
+	r.annotate("post-pull hook succeeded")
+}
+
+// shellCommand returns the program and arguments needed to have the platform's shell run command.
+func shellCommand(command string) (name string, args []string) {
+	if runtime.GOOS == "windows" {
+		return "cmd", []string{"/C", command}
+	}
+	return "sh", []string{"-c", command}
+}
+
+// shellQuote returns s quoted so that the platform's shell, as run by shellCommand, takes it literally as a single
+// word. With cmd, %VARIABLE% references are still expanded.
+func shellQuote(s string) string {
+	if runtime.GOOS == "windows" {
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// expandTemplate replaces the variables {path}, {name}, {branch}, and {remote_url} within text with the repo's path,
+// directory name, current branch, and origin URL, respectively, each passed through quote, if given. Branch names and
+// paths may contain anything a shell would interpret, so text that is to be run by one must quote them.
+func (r *repo) expandTemplate(text string, quote func(string) string) string {
+	if !strings.Contains(text, "{") {
+		return text
+	}
+	var remote string
+	if strings.Contains(text, "{remote_url}") {
+		remote = originURL(r.path)
+	}
+	if quote == nil {
+		quote = func(s string) string { return s }
+	}
+	return strings.NewReplacer(
+		"{path}", quote(r.path),
+		"{name}", quote(filepath.Base(r.path)),
+		"{branch}", quote(r.result.Branch),
+		"{remote_url}", quote(remote),
+	).Replace(text)
+}
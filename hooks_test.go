@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/richardwilkes/toolbox/check"
+)
+
+func TestExpandTemplateQuoting(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the shell used here is sh")
+	}
+	dir := filepath.Join(t.TempDir(), "it's; a `repo`")
+	check.NoError(t, os.Mkdir(dir, 0o755))
+	r := &repo{location: &location{}, path: dir}
+	for _, branch := range []string{
+		"x$(touch pwned)", "x`touch pwned`", "x;touch pwned", "x|touch pwned", "it's", "x'$(touch pwned)'",
+	} {
+		r.result.Branch = branch
+		name, args := shellCommand(r.expandTemplate("printf '%s\\n' {branch} {name} > out", shellQuote))
+		cmd := exec.Command(name, args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		check.NoError(t, err, string(out))
+		_, err = os.Stat(filepath.Join(dir, "pwned"))
+		check.True(t, os.IsNotExist(err), "the branch name %q was run by the shell", branch)
+		var data []byte
+		data, err = os.ReadFile(filepath.Join(dir, "out"))
+		check.NoError(t, err)
+		check.Equal(t, branch+"\n"+filepath.Base(dir)+"\n", string(data))
+	}
+
+	// The arguments given to exec, which don't pass through a shell, are left as they are
+	r.result.Branch = "x$(touch pwned)"
+	check.Equal(t, "x$(touch pwned)", r.expandTemplate("{branch}", nil))
+	check.Equal(t, dir, r.expandTemplate("{path}", nil))
+}
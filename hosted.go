@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/errs"
+	"github.com/richardwilkes/toolbox/xio"
+)
+
+// hostedRepo is a repo listed by a hosting service's API.
+type hostedRepo struct {
+	path string // relative to the target directory
+	url  string
+}
+
+var nextLinkRegex = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// fetchAllPages retrieves the JSON array at url, following "next" links in the response headers, and passes each page
+// to handle. The headers are added to each request.
+func fetchAllPages(ctx context.Context, url string, headers map[string]string, handle func(data []byte) error) error {
+	client := &http.Client{Timeout: time.Minute}
+	for url != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+		if err != nil {
+			return errs.Wrap(err)
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		var rsp *http.Response
+		if rsp, err = client.Do(req); err != nil {
+			return errs.Wrap(err)
+		}
+		var data []byte
+		data, err = io.ReadAll(rsp.Body)
+		xio.CloseIgnoringErrors(rsp.Body)
+		if err != nil {
+			return errs.Wrap(err)
+		}
+		if rsp.StatusCode != http.StatusOK {
+			return errs.Newf("%s: %s", url, rsp.Status)
+		}
+		if err = handle(data); err != nil {
+			return err
+		}
+		url = ""
+		if m := nextLinkRegex.FindStringSubmatch(rsp.Header.Get("Link")); m != nil {
+			url = m[1]
+		}
+	}
+	return nil
+}
+
+// decodePage is a convenience for decoding a page of JSON results and appending them to list.
+func decodePage[T any](list *[]T) func(data []byte) error {
+	return func(data []byte) error {
+		var page []T
+		if err := json.Unmarshal(data, &page); err != nil {
+			return errs.Wrap(err)
+		}
+		*list = append(*list, page...)
+		return nil
+	}
+}
+
+// hostedSource returns a source for the hosted repos, which will be placed within dir. Repos not yet present there
+// are marked as missing, so that they will be cloned.
+func hostedSource(dir string, list func(ctx context.Context) ([]hostedRepo, error)) source {
+	return func(ctx context.Context) ([]string, map[string]*location, string, error) {
+		if dir == "" {
+			var err error
+			if dir, err = os.Getwd(); err != nil {
+				return nil, nil, "", errs.Wrap(err)
+			}
+		}
+		root, err := multirepo.RealPath(dir)
+		if err != nil {
+			root = dir
+		}
+		var s *settings
+		if s, err = rootSettings(root); err != nil {
+			return nil, nil, "", err
+		}
+		var hosted []hostedRepo
+		if hosted, err = list(ctx); err != nil {
+			return nil, nil, "", err
+		}
+		locs := make(map[string]*location)
+		for _, one := range hosted {
+			p := filepath.Join(root, filepath.FromSlash(one.path))
+			if isExcluded(s, root, p) || !isIncluded(root, p) {
+				continue
+			}
+			locs[p] = &location{cfg: s, rel: one.path, url: one.url, missing: !multirepo.IsRepo(p)}
+		}
+		return sortedPaths(locs), locs, root, nil
+	}
+}
+
+// tokenOr returns token, the one configured for gp, if it is set. Otherwise, returns the value of the first non-empty
+// environment variable in names, the ones shared with other tools, or an empty string if there are none.
+func tokenOr(token string, names ...string) string {
+	if token != "" {
+		return token
+	}
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
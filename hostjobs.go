@@ -0,0 +1,85 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+var (
+	hostJobs   []string
+	hostLimits map[string]int
+)
+
+// validateHostJobs parses the per-host limits given with --host-jobs, each in the form host=N.
+func validateHostJobs() error {
+	hostLimits = make(map[string]int, len(hostJobs))
+	for _, one := range hostJobs {
+		host, value, ok := strings.Cut(one, "=")
+		host = strings.ToLower(strings.TrimSpace(host))
+		if !ok || host == "" {
+			return errs.Newf("invalid host limit %q; must be in the form host=N", one)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 1 {
+			return errs.Newf("invalid host limit %q; the limit must be a number greater than zero", one)
+		}
+		hostLimits[host] = n
+	}
+	return nil
+}
+
+// remoteHost returns the host of url, a git remote URL, in lowercase. Returns an empty string for local paths.
+func remoteHost(url string) string {
+	host, _, _ := strings.Cut(normalizeRemoteURL(url), "/")
+	return strings.ToLower(host)
+}
+
+// limitedHost returns the host of the repo's origin, if it has a limit set with --host-jobs, or an empty string
+// otherwise.
+func (r *repo) limitedHost() string {
+	url := r.url
+	if !r.missing {
+		url = originURL(r.path)
+	}
+	host := remoteHost(url)
+	if _, ok := hostLimits[host]; !ok {
+		return ""
+	}
+	return host
+}
+
+// scheduleByHost sends the repos to work in order, except that those on a host already being worked on by as many
+// repos as its limit allows are held back until one of those finishes, as reported on finished. Other repos are sent
+// meanwhile, so that the limit on one host doesn't hold up the rest.
+func scheduleByHost(repos []*repo, work chan<- *repo, finished <-chan *repo) {
+	defer close(work)
+	hosts := make(map[*repo]string, len(repos))
+	for _, r := range repos {
+		hosts[r] = r.limitedHost()
+	}
+	running := make(map[string]int)
+	pending := slices.Clone(repos)
+	for len(pending) != 0 {
+		next := -1
+		for i, r := range pending {
+			if host := hosts[r]; host == "" || running[host] < hostLimits[host] {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			running[hosts[<-finished]]--
+			continue
+		}
+		select {
+		case work <- pending[next]:
+			running[hosts[pending[next]]]++
+			pending = slices.Delete(pending, next, next+1)
+		case r := <-finished:
+			running[hosts[r]]--
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/xio/term"
+)
+
+const skippedPrefix = "skipped due to "
+
+var icons bool
+
+// icon returns the glyph that summarizes the repo's progress.
+func (r *repo) icon() string {
+	if !r.finished {
+		return "↻"
+	}
+	switch r.result.Outcome {
+	case multirepo.Updated:
+		return "✔"
+	case multirepo.Unchanged:
+		return "•"
+	case multirepo.Failed, multirepo.Aborted, multirepo.TimedOut:
+		return "✖"
+	default:
+		return "✱"
+	}
+}
+
+// drawLabel displays the repo's name, preceded by its icon when --icons is in effect. As this erases anything to its
+// right, finish uses it to redraw the whole row so that the icon reflects the outcome.
+func (r *repo) drawLabel() {
+	col := r.col
+	r.col = 1
+	if icons {
+		color := textColor
+		if r.finished {
+			color = r.statusColor
+		}
+		r.show(r.icon(), color, term.Bold)
+		r.col = 3
+	}
+	r.show(r.label, textColor, term.Normal)
+	r.col = col
+}
+
+// compactStatus returns the status shortened for display alongside an icon, which already conveys what the removed text
+// did.
+func compactStatus(status string) string {
+	return strings.TrimPrefix(status, skippedPrefix)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/cmdline"
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+// ignoredRepos holds the paths of the repos recorded by the ignore command, once loaded.
+var ignoredRepos map[string]bool
+
+type ignoreCmd struct {
+	remove bool
+}
+
+func (c *ignoreCmd) Name() string {
+	if c.remove {
+		return "unignore"
+	}
+	return "ignore"
+}
+
+func (c *ignoreCmd) Usage() string {
+	if c.remove {
+		return "Removes repos from the list of those that gp leaves alone."
+	}
+	return "Records repos that gp should leave alone, such as archived or broken clones. They are listed as ignored, " +
+		"rather than processed, by other commands."
+}
+
+func (c *ignoreCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	cl.UsageSuffix = "<one or more paths to git repos>"
+	paths := cl.Parse(args)
+	if len(paths) == 0 {
+		cl.FatalMsg("At least one path must be specified")
+	}
+	list, err := loadIgnored()
+	if err != nil {
+		cl.FatalMsg(multirepo.ErrorText(err))
+	}
+	// Check all of the paths before changing anything, so that an error doesn't leave the list partially updated
+	resolvedPaths := make([]string, len(paths))
+	for i, p := range paths {
+		if resolvedPaths[i], err = multirepo.RealPath(p); err != nil {
+			cl.FatalMsg(multirepo.ErrorText(errs.NewWithCause("unable to resolve "+p, err)))
+		}
+		if !c.remove && !multirepo.IsRepo(resolvedPaths[i]) {
+			cl.FatalMsg(resolvedPaths[i] + " is not a git repo")
+		}
+	}
+	for _, resolved := range resolvedPaths {
+		switch {
+		case c.remove && !slices.Contains(list, resolved):
+			fmt.Printf("%s was not being ignored\n", resolved)
+		case c.remove:
+			list = slices.DeleteFunc(list, func(s string) bool { return s == resolved })
+			fmt.Printf("No longer ignoring %s\n", resolved)
+		case slices.Contains(list, resolved):
+			fmt.Printf("%s is already being ignored\n", resolved)
+		default:
+			list = append(list, resolved)
+			fmt.Printf("Ignoring %s\n", resolved)
+		}
+	}
+	if err = saveIgnored(list); err != nil {
+		cl.FatalMsg(multirepo.ErrorText(err))
+	}
+	return nil
+}
+
+// ignoredPath returns the path to the file holding the list of ignored repos, which lives alongside the user's
+// configuration file.
+func ignoredPath() string {
+	return filepath.Join(filepath.Dir(userConfigPath()), "ignored")
+}
+
+// loadIgnored returns the paths of the ignored repos, one per line in the file.
+func loadIgnored() ([]string, error) {
+	data, err := os.ReadFile(ignoredPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errs.Wrap(err)
+	}
+	var list []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			list = append(list, line)
+		}
+	}
+	return list, nil
+}
+
+func saveIgnored(list []string) error {
+	path := ignoredPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errs.Wrap(err)
+	}
+	slices.Sort(list)
+	var buffer strings.Builder
+	for _, p := range list {
+		buffer.WriteString(p)
+		buffer.WriteByte('\n')
+	}
+	if err := os.WriteFile(path, []byte(buffer.String()), 0o644); err != nil {
+		return errs.Wrap(err)
+	}
+	return nil
+}
+
+// loadIgnoredRepos loads the list of ignored repos for use by isIgnored.
+func loadIgnoredRepos() error {
+	list, err := loadIgnored()
+	if err != nil {
+		return err
+	}
+	ignoredRepos = make(map[string]bool, len(list))
+	for _, p := range list {
+		ignoredRepos[p] = true
+	}
+	return nil
+}
+
+// isIgnored returns true if the repo at path has been recorded by the ignore command.
+func isIgnored(path string) bool {
+	return ignoredRepos[path]
+}
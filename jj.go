@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+var (
+	useJJ    bool
+	forceGit bool
+)
+
+// isJJRepo returns true if the repo is colocated with a Jujutsu repo, whose view of it can be thrown out of sync by
+// changes git makes behind its back.
+func (r *repo) isJJRepo() bool {
+	fi, err := os.Stat(filepath.Join(r.path, ".jj"))
+	return err == nil && fi.IsDir()
+}
+
+// jjPull updates a colocated Jujutsu repo using jj: its remotes are fetched, after which, unless only fetching, the
+// working copy's changes are rebased onto the trunk.
+func jjPull(r *repo) {
+	before, err := r.jjTrunk()
+	if err != nil {
+		r.fail("skipped due to error", err)
+		return
+	}
+	if dryRun {
+		r.notice("would fetch and rebase with jj")
+		return
+	}
+	if _, err = r.jj("git", "fetch"); err != nil {
+		r.fail("failed to fetch with jj", err)
+		return
+	}
+	var after string
+	if after, err = r.jjTrunk(); err != nil {
+		r.fail("skipped due to error", err)
+		return
+	}
+	if after == before {
+		r.succeeded("no changes")
+		return
+	}
+	if fetchOnly {
+		r.notice("fetched with jj")
+		return
+	}
+	if _, err = r.jj("rebase", "--destination", "trunk()"); err != nil {
+		r.fail("failed to rebase with jj", err)
+		return
+	}
+	r.changed("rebased onto the trunk with jj")
+}
+
+// jjTrunk returns the id of the commit jj considers the trunk, that of the default branch on the remote.
+func (r *repo) jjTrunk() (string, error) {
+	return r.jj("log", "--no-graph", "--revisions", "trunk()", "--template", "commit_id")
+}
+
+// jj runs jj with args in the repo, without pausing for a pager or using color.
+func (r *repo) jj(args ...string) (string, error) {
+	return r.runner().Run(r.ctx, "jj", append([]string{"--no-pager", "--color", "never"}, args...)...)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/atexit"
+	"github.com/richardwilkes/toolbox/errs"
+	"github.com/richardwilkes/toolbox/xio"
+)
+
+var (
+	// locking is cleared by the commands that only inspect the repos, which can safely run alongside others.
+	locking     = true
+	noLock      bool
+	waitForLock bool
+)
+
+// lockWorkspace prevents other runs of gp from working on the same roots until this one exits, for which it waits with
+// --wait and otherwise fails. Locks left behind by runs that didn't exit cleanly are removed.
+func lockWorkspace(roots []string) error {
+	if !locking || noLock {
+		return nil
+	}
+	path := lockPath(roots)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errs.NewWithCause("unable to create "+filepath.Dir(path), err)
+	}
+	waiting := false
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_, err = fmt.Fprintf(f, "%d\n%s\n", os.Getpid(), time.Now().Format(time.RFC3339))
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return errs.NewWithCause("unable to write lock "+path, err)
+			}
+			removeOldStaleMarkers(path)
+			atexit.Register(func() {
+				if removeErr := os.Remove(path); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+					fmt.Fprintln(os.Stderr,
+						multirepo.ErrorText(errs.NewWithCause("unable to remove lock "+path, removeErr)))
+				}
+			})
+			return nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return errs.NewWithCause("unable to create lock "+path, err)
+		}
+		pid, started, held, id := lockHolder(path)
+		if !held {
+			if err = removeStaleLock(path, id); err != nil {
+				return err
+			}
+			continue
+		}
+		if !waitForLock {
+			return errs.Newf("another gp run (process %d, started %s) is already working on these repos; use --wait "+
+				"to wait for it to finish, or --no-lock to run anyway", pid, started.Format(time.DateTime))
+		}
+		if !waiting {
+			waiting = true
+			fmt.Fprintf(os.Stderr, "Waiting for another gp run (process %d) to finish…\n", pid)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// lockPath returns the path of the lock for roots, which lives alongside the user's configuration file rather than
+// within the roots, where it would disturb the discovery cache.
+func lockPath(roots []string) string {
+	resolved := make([]string, len(roots))
+	for i, root := range roots {
+		if p, err := multirepo.RealPath(root); err == nil {
+			root = p
+		}
+		resolved[i] = root
+	}
+	slices.Sort(resolved)
+	sum := sha256.Sum256([]byte(strings.Join(resolved, "\n")))
+	return filepath.Join(filepath.Dir(userConfigPath()), "locks", hex.EncodeToString(sum[:16])+".lock")
+}
+
+// lockHolder returns the process that holds the lock at path and when it started, along with an id that distinguishes
+// this lock from any other that is later created at the same path. held is false if that process is no longer running.
+// A lock that can't be read is considered held for a few seconds, since it may still be being written.
+func lockHolder(path string) (pid int, started time.Time, held bool, id string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, time.Time{}, !errors.Is(err, os.ErrNotExist), ""
+	}
+	defer xio.CloseIgnoringErrors(f)
+	// The contents and the modification time are both taken from the same open file, so that they can't be those of
+	// different locks
+	var fi os.FileInfo
+	if fi, err = f.Stat(); err != nil {
+		return 0, time.Time{}, true, ""
+	}
+	var data []byte
+	if data, err = io.ReadAll(f); err != nil {
+		return 0, time.Time{}, true, ""
+	}
+	sum := sha256.Sum256(fmt.Appendf(data, "\n%d", fi.ModTime().UnixNano()))
+	id = hex.EncodeToString(sum[:8])
+	lines := strings.Split(string(data), "\n")
+	if len(lines) >= 2 {
+		if pid, err = strconv.Atoi(lines[0]); err == nil {
+			if started, err = time.Parse(time.RFC3339, lines[1]); err == nil {
+				return pid, started, processAlive(pid), id
+			}
+		}
+	}
+	return 0, time.Time{}, time.Since(fi.ModTime()) < 5*time.Second, id
+}
+
+// staleMarkerSuffix follows the lock's path in the names of the markers that record which stale locks have been taken
+// over.
+const staleMarkerSuffix = ".stale-"
+
+// removeStaleLock removes the lock at path, which was found to be stale and to have the given id, unless another run is
+// already doing so. Several runs may find the same lock stale at once, and by the time the slower of them acts, one of
+// the others may have replaced it with a lock of its own. Only the run that manages to create the marker for this
+// particular lock removes it, so the replacement is never removed in its place. The markers must outlast any run that
+// could still act on having seen the lock, so they are only cleared once old.
+func removeStaleLock(path, id string) error {
+	if id == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path+staleMarkerSuffix+id, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			// Another run is taking over the lock
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		}
+		return errs.NewWithCause("unable to take over stale lock "+path, err)
+	}
+	xio.CloseIgnoringErrors(f)
+	if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errs.NewWithCause("unable to remove stale lock "+path, err)
+	}
+	return nil
+}
+
+// removeOldStaleMarkers removes the markers left by removeStaleLock for the lock at path that are more than an hour
+// old.
+func removeOldStaleMarkers(path string) {
+	markers, err := filepath.Glob(path + staleMarkerSuffix + "*")
+	if err != nil {
+		return
+	}
+	for _, marker := range markers {
+		if fi, statErr := os.Stat(marker); statErr == nil && time.Since(fi.ModTime()) > time.Hour {
+			if removeErr := os.Remove(marker); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+				fmt.Fprintln(os.Stderr, multirepo.ErrorText(errs.NewWithCause("unable to remove "+marker, removeErr)))
+			}
+		}
+	}
+}
+
+// processAlive returns true if the process with the given id is running.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// FindProcess only succeeds on Windows for processes that exist
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/richardwilkes/toolbox/check"
+)
+
+func TestStaleLockTakeover(t *testing.T) {
+	// A process that has exited leaves a lock that is stale
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	check.NoError(t, cmd.Run())
+	path := filepath.Join(t.TempDir(), "roots.lock")
+	data := fmt.Appendf(nil, "%d\n%s\n", cmd.Process.Pid, time.Now().Format(time.RFC3339))
+	check.NoError(t, os.WriteFile(path, data, 0o644))
+
+	// Two runs both find it stale
+	_, _, heldA, idA := lockHolder(path)
+	_, _, heldB, idB := lockHolder(path)
+	check.False(t, heldA)
+	check.False(t, heldB)
+	check.Equal(t, idA, idB)
+
+	// The first takes it over and creates its own
+	check.NoError(t, removeStaleLock(path, idA))
+	live := fmt.Sprintf("%d\n%s\n", os.Getpid(), time.Now().Format(time.RFC3339))
+	check.NoError(t, os.WriteFile(path, []byte(live), 0o644))
+
+	// The second, acting on what it saw earlier, must leave the new lock alone
+	check.NoError(t, removeStaleLock(path, idB))
+	data, err := os.ReadFile(path)
+	check.NoError(t, err)
+	check.Equal(t, live, string(data))
+	_, _, held, idLive := lockHolder(path)
+	check.True(t, held)
+	check.NotEqual(t, idA, idLive)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/atexit"
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+var logPath string
+
+var commandLog struct {
+	lock sync.Mutex
+	file *os.File
+}
+
+// openCommandLog opens the log file, if one was requested, for appending. It is closed upon exit.
+func openCommandLog() error {
+	if logPath == "" {
+		return nil
+	}
+	f, err := os.OpenFile(expandHome(logPath), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return errs.NewWithCause("unable to open log file", err)
+	}
+	commandLog.file = f
+	atexit.Register(func() {
+		commandLog.lock.Lock()
+		defer commandLog.lock.Unlock()
+		if commandLog.file != nil {
+			if closeErr := commandLog.file.Close(); closeErr != nil {
+				fmt.Fprintln(os.Stderr, multirepo.ErrorText(errs.NewWithCause("unable to close log file", closeErr)))
+			}
+			commandLog.file = nil
+		}
+	})
+	return nil
+}
+
+// logCommand appends a record of the completed command c, which began at start and produced out, to the log file, if
+// one is open.
+func logCommand(c *exec.Cmd, start time.Time, out string, err error) {
+	status := "exit status -1"
+	if c.ProcessState != nil {
+		status = fmt.Sprintf("exit status %d", c.ProcessState.ExitCode())
+	}
+	if err != nil && (c.ProcessState == nil || c.ProcessState.ExitCode() == -1) {
+		status = multirepo.ErrorText(err)
+	}
+	writeLogEntry(c.String(), c.Dir, start, status, out)
+}
+
+// writeLogEntry appends a record of a completed command, described by cmd, to the log file, if one is open.
+func writeLogEntry(cmd, dir string, start time.Time, status, out string) {
+	commandLog.lock.Lock()
+	defer commandLog.lock.Unlock()
+	if commandLog.file == nil {
+		return
+	}
+	var buffer strings.Builder
+	fmt.Fprintf(&buffer, "%s %s\n", start.Format(time.RFC3339Nano), cmd)
+	fmt.Fprintf(&buffer, "  dir: %s\n", dir)
+	fmt.Fprintf(&buffer, "  took %v, %s\n", time.Since(start).Round(time.Millisecond), status)
+	if out != "" {
+		for _, line := range strings.Split(out, "\n") {
+			buffer.WriteString("  | ")
+			buffer.WriteString(line)
+			buffer.WriteByte('\n')
+		}
+	}
+	if _, writeErr := commandLog.file.WriteString(buffer.String()); writeErr != nil {
+		fmt.Fprintln(os.Stderr, multirepo.ErrorText(errs.NewWithCause("unable to write to log file", writeErr)))
+		commandLog.file = nil
+	}
+}
@@ -1,302 +1,518 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"runtime"
-	"sort"
+	"slices"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/atexit"
 	"github.com/richardwilkes/toolbox/cmdline"
 	"github.com/richardwilkes/toolbox/errs"
-	"github.com/richardwilkes/toolbox/txt"
-	"github.com/richardwilkes/toolbox/xio"
+	"github.com/richardwilkes/toolbox/xio/fs/paths"
 	"github.com/richardwilkes/toolbox/xio/term"
-	"github.com/yookoala/realpath"
 )
 
-type repo struct {
-	path    string
-	printer chan *msgInfo
-	row     int
-	col     int
-}
-
-type msgInfo struct {
-	msg   string
-	row   int
-	col   int
-	color term.Color
-	style term.Style
-}
+const (
+	pathsUsage      = "[zero or more paths to the parent directories of git repos]"
+	exitFailed      = 1
+	exitSkipped     = 2
+	exitInterrupted = 130
+)
 
 var (
-	blue    = term.Blue
-	magenta = term.Magenta
-	red     = term.Red
-	black   = term.Black
+	depth      = 1
+	jobs       = runtime.NumCPU()
+	recursive  bool
+	plain      bool
+	jsonOut    bool
+	strict     bool
+	maxRuntime time.Duration
+	slowest    int
+	verbose    bool
+	watch      time.Duration
 )
 
+var stdOptions = []string{"-h", "--help", "-v", "--version", "-V", "--Version"}
+
 func main() {
+	if socket := os.Getenv(askpassSocketEnv); socket != "" {
+		var prompt string
+		if len(os.Args) > 1 {
+			prompt = os.Args[1]
+		}
+		atexit.Exit(runAskpass(socket, prompt))
+	}
 	cmdline.AppVersion = "1.1"
 	cmdline.CopyrightStartYear = "2022"
 	cmdline.CopyrightHolder = "Richard A. Wilkes"
 	cmdline.AppIdentifier = "com.trollworks.gp"
 	cl := cmdline.New(true)
-	cl.Description = "Pulls unmodified git repos"
-	cl.UsageSuffix = "[zero or more paths to the parent directories of git repos]"
-	paths := cl.Parse(os.Args[1:])
+	cl.Description = "Performs git operations across many repos at once"
+	completion := &completionCmd{}
+	cmds := []cmdline.Cmd{
+		&pullCmd{},
+		&cloneCmd{},
+		&fetchCmd{},
+		&statusCmd{},
+		&execCmd{},
+		&cleanupCmd{},
+		&switchCmd{},
+		&daemonCmd{},
+		&maintainCmd{},
+		&fsckCmd{},
+		&auditCmd{},
+		&bundleCmd{},
+		&duCmd{},
+		&partialCmd{},
+		&tagsCmd{},
+		&ignoreCmd{},
+		&ignoreCmd{remove: true},
+		&githubCmd{},
+		&gitlabCmd{},
+		&historyCmd{},
+		&historyCmd{last: true},
+		completion,
+	}
+	completion.cmds = cmds
+	for _, cmd := range cmds {
+		cl.AddCommand(cmd)
+	}
 
-	// If no paths specified, use the current directory
-	if len(paths) == 0 {
-		wd, err := os.Getwd()
-		if err != nil {
-			return
-		}
-		paths = append(paths, wd)
+	if err := loadConfigs(); err != nil {
+		cl.FatalMsg(multirepo.ErrorText(err))
 	}
 
-	// Collect the git repos to process -- we only look one level deep
-	set := make(map[string]struct{})
-	for _, path := range paths {
-		for _, entry := range readDir(path) {
-			if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
-				p := filepath.Join(path, entry.Name())
-				if fi, err := os.Stat(filepath.Join(p, ".git")); err == nil && fi.IsDir() {
-					if p, err = realpath.Realpath(p); err == nil {
-						set[p] = struct{}{}
-					}
-				}
-			}
+	// Without a command, behave as a pull, as gp always has
+	args := os.Args[1:]
+	if len(args) == 0 || !isCmdName(cmds, args[0]) && !slices.Contains(stdOptions, args[0]) {
+		args = append([]string{(&pullCmd{}).Name()}, args...)
+	}
+	commandName = args[0]
+	cl.FatalIfError(cl.RunCommand(cl.Parse(args)))
+	atexit.Exit(0)
+}
+
+func isCmdName(cmds []cmdline.Cmd, name string) bool {
+	if name == "help" {
+		return true
+	}
+	for _, cmd := range cmds {
+		if cmd.Name() == name {
+			return true
 		}
 	}
-	list := make([]string, 0, len(set))
-	longest := 0
-	for p := range set {
-		list = append(list, p)
-		if len(paths) == 1 {
-			p = filepath.Base(p)
+	return false
+}
+
+// addCommonOptions adds the options shared by all commands.
+func addCommonOptions(cl *cmdline.CmdLine) {
+	cl.UsageSuffix = pathsUsage
+	cl.NewGeneralOption(&depth).SetName("depth").SetArg("N").
+		SetUsage("The number of directory levels below each path to search for git repos")
+	cl.NewGeneralOption(&jobs).SetSingle('j').SetName("jobs").SetArg("N").
+		SetUsage("The maximum number of repos to process concurrently")
+	cl.NewGeneralOption(&hostJobs).SetName("host-jobs").SetArg("host=N").
+		SetUsage("Limit the number of repos whose origin is on the host that are processed at once, independently of " +
+			"--jobs, while repos on other hosts continue to be processed at full speed. May be " +
+			"specified more than once")
+	cl.NewGeneralOption(&recursive).SetSingle('r').SetName("recursive").
+		SetUsage("Search for git repos at any depth below each path")
+	cl.NewGeneralOption(&includes).SetName("include").SetArg("glob").
+		SetUsage("Only process repos whose directory name or path relative to the search path matches the glob. May " +
+			"be specified more than once")
+	cl.NewGeneralOption(&excludes).SetName("exclude").SetArg("glob").
+		SetUsage("Skip repos whose directory name or path relative to the search path matches the glob. May be " +
+			"specified more than once and takes precedence over --include")
+	cl.NewGeneralOption(&manifest).SetName("manifest").SetArg("file").
+		SetUsage("Process the repos listed in the file rather than searching for them. The file may be YAML (.yaml " +
+			"or .yml) or plain text with one path, optionally followed by a URL, per line")
+	cl.NewGeneralOption(&refresh).SetName("refresh").
+		SetUsage("Search the directories for repos again, rather than reusing what was found last time in those " +
+			"whose contents haven't changed since")
+	cl.NewGeneralOption(&remoteMatch).SetName("remote-match").SetArg("glob").
+		SetUsage("Only process repos whose origin URL matches the glob, e.g. github.com/myorg/*")
+	cl.NewGeneralOption(&plain).SetName("plain").
+		SetUsage("Print one line per repo as it completes rather than updating the display in place. This is the " +
+			"default when the output is not a terminal")
+	cl.NewGeneralOption(&onlyChanged).SetName("only-changed").
+		SetUsage("Leave the repos that were left unchanged, such as those with no changes to pull, out of the " +
+			"display, so that only those deserving attention remain. The display that updates in place drops them " +
+			"once all repos have finished")
+	cl.NewGeneralOption(&colorMode).SetName("color").SetArg("when").
+		SetUsage(fmt.Sprintf("Whether to use color and update the display in place: %s, %s, or %s. In %s mode, color "+
+			"is used only when the output is a terminal and the NO_COLOR environment variable isn't set. Without "+
+			"color, one line is printed per repo as it completes", colorAuto, colorAlways, colorNever, colorAuto))
+	cl.NewGeneralOption(&icons).SetName("icons").
+		SetUsage("Start each row with a glyph summarizing the repo's progress (✔ updated, • unchanged, ✖ failed, ✱ " +
+			"skipped, ↻ in progress) and omit the details those make redundant, such as the time taken")
+	cl.NewGeneralOption(&group).SetName("group").
+		SetUsage("Group the repos by their parent directory, showing each directory once as a header above the names " +
+			"of the repos within it")
+	cl.NewGeneralOption(&sortBy).SetName("sort").SetArg("key").
+		SetUsage(fmt.Sprintf("Order the results by %s, %s (failures first), %s (slowest first), %s (most recent "+
+			"commit first), or %s (largest on disk first, as measured by du). The display that updates in place "+
+			"keeps the order in which the repos were found, but line-oriented output is held until all repos have "+
+			"finished so that it can be printed in this order", sortName, sortStatus, sortDuration, sortMTime,
+			sortSize))
+	cl.NewGeneralOption(&selectRepos).SetName("select").
+		SetUsage("Once the repos have been found, present a list of them, all initially selected, from which those " +
+			"to process can be chosen")
+	cl.NewGeneralOption(&tui).SetName("tui").
+		SetUsage("Display the repos in a full-screen, interactive view that can be scrolled and filtered by outcome, " +
+			"and that shows the full output of the git commands run for the selected repo")
+	cl.NewGeneralOption(&jsonOut).SetName("json").
+		SetUsage("Suppress the display and instead emit a JSON array of the results once all repos have been processed")
+	cl.NewGeneralOption(&reportPath).SetName("report").SetArg("file").
+		SetUsage("Once all repos have been processed, write a report of the run to the file, replacing any previous " +
+			"one, for consumption by CI systems. It lists each repo's outcome, duration, commits pulled, and any " +
+			"error, in JUnit XML if the file's name ends in .xml and in JSON otherwise")
+	cl.NewGeneralOption(&strict).SetName("strict").
+		SetUsage(fmt.Sprintf("Exit with status %d if any repos were skipped, such as for having local changes. "+
+			"Failures always result in an exit status of %d", exitSkipped, exitFailed))
+	cl.NewGeneralOption(&useGoGit).SetName("go-git").
+		SetUsage("Perform the most common operations, such as determining the branch, checking for local changes, " +
+			"comparing against the upstream, fetching, and fast-forwarding, in-process with go-git rather than by " +
+			"running git, which avoids starting hundreds of processes and allows use where git isn't installed. " +
+			"Anything else, and anything go-git can't manage, such as fetches that need a credential helper, still " +
+			"runs git. LFS and other filters aren't applied to the files that go-git checks out")
+	cl.NewGeneralOption(&sshMultiplex).SetName("ssh-multiplex").
+		SetUsage("Share a single SSH connection to each host among all of the repos using it, rather than each " +
+			"making its own, by having the first repo for a host connect before the rest. This avoids repeating the " +
+			"handshake and tripping limits on the rate of new connections. Operations performed with go-git make " +
+			"their own connections")
+	cl.NewGeneralOption(&rewriteRemote).SetName("rewrite-remote").SetArg("form").
+		SetUsage(fmt.Sprintf("Have git reach each repo's origin using the given form, %s or %s, rewriting URLs in "+
+			"the other form for the duration of the run, such as where a network blocks SSH. The repos' "+
+			"configuration is left unchanged", rewriteSSH, rewriteHTTPS))
+	if locking {
+		cl.NewGeneralOption(&waitForLock).SetName("wait").
+			SetUsage("Should another run of gp already be working on the same paths, wait for it " +
+				"to finish rather than exiting")
+		cl.NewGeneralOption(&noLock).SetName("no-lock").
+			SetUsage("Run even if another run of gp is already working on the same paths")
+	}
+	cl.NewGeneralOption(&preflightCheck).SetName("preflight").
+		SetUsage("Before processing any repos, check once that each distinct host their origins are on can be " +
+			"reached, connecting to it and, for SSH, awaiting the server's greeting, so that the repos on a host " +
+			"that can't be fail at once rather than each waiting out its own timeouts and retries. Hosts reached " +
+			"through a proxy aren't checked")
+	cl.NewGeneralOption(&maxRuntime).SetName("max-runtime").SetArg("duration").
+		SetUsage("The maximum time the whole run may take. Repos still being processed when it elapses are marked as " +
+			"timed out. Zero means no limit")
+	cl.NewGeneralOption(&skipStale).SetName("skip-stale").SetArg("duration").
+		SetUsage("Skip the repos that have had no activity for this long, such as 2160h for 90 days: no commits, " +
+			"locally or fetched from their upstream, and no checkouts or staged changes. Zero means " +
+			"no repos are skipped")
+	cl.NewGeneralOption(&watch).SetName("watch").SetArg("interval").
+		SetUsage("Keep running, repeating the whole process each time the interval elapses, until interrupted")
+	cl.NewGeneralOption(&notify).SetName("notify").
+		SetUsage("Display a desktop notification listing the repos that were updated or failed, if any, after each run")
+	cl.NewGeneralOption(&webhookURL).SetName("webhook").SetArg("url").
+		SetUsage("After each run, post a JSON summary of it to the URL, listing the repos that were updated, " +
+			"skipped, and failed")
+	cl.NewGeneralOption(&slackWebhookURL).SetName("slack-webhook").SetArg("url").
+		SetUsage("After each run, post a summary of it to the Slack incoming webhook at the URL")
+	cl.NewGeneralOption(&verbose).SetName("verbose").
+		SetUsage("Once all repos have been processed, print the complete output of the commands run for those that " +
+			"failed or were updated")
+	cl.NewGeneralOption(&logPath).SetName("log").SetArg("file").
+		SetUsage("Append a timestamped record of each command run, including its directory, duration, exit status, " +
+			"and full output, to the file")
+	cl.NewGeneralOption(&slowest).SetName("slowest").SetArg("N").
+		SetUsage("List the N repos that took the longest to process after the summary")
+	addSettingsOptions(cl)
+}
+
+// source provides the list of repos to process, along with what is known about each and the root to use when
+// computing display names.
+type source func(ctx context.Context) (list []string, locs map[string]*location, root string, err error)
+
+// run discovers the git repos within paths and applies action to each of them, displaying the results as they arrive.
+func run(paths []string, action func(r *repo)) {
+	if len(paths) == 0 && manifest == "" {
+		if paths = defaultRoots(); len(paths) == 0 {
+			return
 		}
-		if longest < len(p) {
-			longest = len(p)
+	}
+	if recursive {
+		depth = 0
+	}
+	for _, patterns := range [][]string{includes, excludes} {
+		if err := multirepo.ValidatePatterns(patterns); err != nil {
+			fmt.Fprintln(os.Stderr, multirepo.ErrorText(err))
+			atexit.Exit(exitFailed)
 		}
 	}
-	sort.Slice(list, func(i, j int) bool { return txt.NaturalLess(list[i], list[j], true) })
+	roots := paths
+	if manifest != "" {
+		roots = []string{manifest}
+	}
+	process(roots, func(_ context.Context) (list []string, locs map[string]*location, root string, err error) {
+		if manifest != "" {
+			if len(paths) != 0 {
+				return nil, nil, "", errs.New("paths may not be specified when using --manifest")
+			}
+			return loadManifest(manifest)
+		}
+		return discover(paths)
+	}, action)
+}
 
-	if runtime.GOOS == "darwin" {
-		if out, err := exec.Command("defaults", "read", "-g", "AppleInterfaceStyle").Output(); err == nil && bytes.HasPrefix(out, []byte("Dark")) {
-			black = term.White
-			blue = term.Cyan
+// process applies action to each of the repos provided by src, displaying the results as they arrive. With --watch,
+// this is repeated on the interval until interrupted. The roots, from which src finds the repos, are locked against
+// other runs for the duration.
+func process(roots []string, src source, action func(r *repo)) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	// Check the options and prepare for the run, in order, stopping at the first problem
+	for _, step := range []func() error{
+		overrides.validate,
+		validateColorMode,
+		validateSortBy,
+		validateRewriteRemote,
+		validateHostJobs,
+		loadIgnoredRepos,
+		openCommandLog,
+		validateWebhooks,
+		func() error { return lockWorkspace(roots) },
+		startAskpass,
+		startSSHMultiplexing,
+	} {
+		if err := step(); err != nil {
+			fmt.Fprintln(os.Stderr, multirepo.ErrorText(err))
+			atexit.Exit(exitFailed)
 		}
 	}
 
-	var printerWG sync.WaitGroup
-	printer := make(chan *msgInfo, len(list))
-	printerWG.Add(1)
-	t := term.NewANSI(os.Stdout)
-	t.Clear()
-	go processMsgs(&printerWG, t, printer)
+	// Cancel any outstanding work when interrupted. A second interrupt gets the default behavior.
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-sigCtx.Done()
+		stop()
+	}()
+	ctx := sigCtx
+	if maxRuntime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(sigCtx, maxRuntime)
+		defer cancel()
+	}
 
-	var wg sync.WaitGroup
-	repos := make([]*repo, len(list))
-	format := fmt.Sprintf("%%%ds:", longest)
-	for i, p := range list {
-		repos[i] = &repo{
-			path:    p,
-			printer: printer,
-			row:     i + 1,
-			col:     longest + 3,
+	var repos []*repo
+	for {
+		var err error
+		if repos, err = processOnce(ctx, src, action); err != nil {
+			fmt.Fprintln(os.Stderr, multirepo.ErrorText(err))
+			if watch <= 0 {
+				atexit.Exit(exitFailed)
+			}
+		} else if cycleDone != nil {
+			cycleDone(repos)
 		}
-		if len(paths) == 1 {
-			p = filepath.Base(p)
+		if watch <= 0 || ctx.Err() != nil {
+			break
 		}
-		printer <- &msgInfo{
-			msg:   fmt.Sprintf(format, p),
-			row:   i + 1,
-			col:   1,
-			color: black,
-			style: term.Normal,
+		if !jsonOut {
+			fmt.Printf("Next sync at %s\n", time.Now().Add(watch).Format(time.TimeOnly))
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(watch):
+		}
+		if ctx.Err() != nil {
+			break
 		}
-		wg.Add(1)
-		go processRepo(&wg, repos[i])
 	}
-	wg.Wait()
-	close(printer)
-	printerWG.Wait()
+	if sigCtx.Err() != nil {
+		atexit.Exit(exitInterrupted)
+	}
+	atexit.Exit(exitStatus(repos))
 }
 
-func readDir(path string) []os.DirEntry {
-	f, err := os.Open(path)
+// processOnce applies action to each of the repos provided by src a single time, displaying the results as they
+// arrive, followed by the summary.
+func processOnce(ctx context.Context, src source, action func(r *repo)) ([]*repo, error) {
+	start := time.Now()
+	list, locs, root, err := src(ctx)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	defer xio.CloseIgnoringErrors(f)
-	var entries []os.DirEntry
-	if entries, err = f.ReadDir(-1); err != nil {
-		return nil
+	if remoteMatch != "" {
+		list = filterByRemote(list, locs, remoteMatch)
 	}
-	return entries
-}
+	if list, err = chooseRepos(list, root); err != nil {
+		return nil, err
+	}
+	rows := layoutRows(list, root)
 
-func processMsgs(wg *sync.WaitGroup, t *term.ANSI, printer chan *msgInfo) {
-	defer wg.Done()
-	maxRow := 1
-	for m := range printer {
-		if maxRow < m.row {
-			maxRow = m.row
-		}
-		t.Foreground(m.color, m.style)
-		t.Position(m.row, m.col)
-		msg := m.msg
-		if i := strings.Index(msg, "\n"); i != -1 {
-			msg = msg[:i]
+	adjustColorsForTheme()
+
+	var printerWG sync.WaitGroup
+	printer := make(chan *msgInfo, len(list))
+	printerWG.Add(1)
+	var t *ansi
+	// With --sort, line-oriented output is held until all of the repos have finished, so that it can be printed in
+	// order
+	var deferred map[int]string
+	if sortBy != "" {
+		deferred = make(map[int]string)
+	}
+	switch {
+	case jsonOut:
+		go discardMsgs(&printerWG, printer)
+	case !useColor():
+		go processPlainMsgs(&printerWG, printer, false, deferred)
+	case tui && watch <= 0 && isTerminal(os.Stdout) && isTerminal(os.Stdin):
+		t = newANSI(os.Stdout)
+		go processTUI(&printerWG, printer)
+	case plain || !isTerminal(os.Stdout):
+		t = newANSI(os.Stdout)
+		go processPlainMsgs(&printerWG, printer, true, deferred)
+	default:
+		// Updating in place requires all of the rows to fit on screen; if they don't, print each once it's finished
+		t = newANSI(os.Stdout)
+		if _, height := terminalSize(); len(rows) >= height {
+			go processPlainMsgs(&printerWG, printer, true, deferred)
+		} else {
+			t.Clear()
+			liveProgress = true
+			go processMsgs(&printerWG, t, printer)
 		}
-		fmt.Print(msg)
-		t.EraseLineToEnd()
 	}
-	t.Reset()
-	t.Position(maxRow+1, 1)
-}
 
-func processRepo(wg *sync.WaitGroup, r *repo) {
-	defer wg.Done()
-	branch, err := r.git("branch", "--show-current")
-	if err != nil {
-		r.printer <- &msgInfo{
-			msg:   "skipped due to error: " + err.Error(),
-			row:   r.row,
-			col:   r.col,
-			color: red,
-			style: term.Bold,
+	repos := make([]*repo, 0, len(list))
+	for i, info := range rows {
+		if info.path == "" {
+			printer <- &msgInfo{
+				msg:    info.label,
+				row:    i + 1,
+				col:    1,
+				color:  textColor,
+				style:  term.Bold,
+				header: true,
+			}
+			continue
 		}
-		return
-	}
-	r.printer <- &msgInfo{
-		msg:   "[",
-		row:   r.row,
-		col:   r.col,
-		color: black,
-		style: term.Normal,
-	}
-	r.col++
-	r.printer <- &msgInfo{
-		msg:   branch,
-		row:   r.row,
-		col:   r.col,
-		color: black,
-		style: term.Bold,
-	}
-	r.col += len(branch)
-	r.printer <- &msgInfo{
-		msg:   "]",
-		row:   r.row,
-		col:   r.col,
-		color: black,
-		style: term.Normal,
-	}
-	r.col += 2
-	var out string
-	if out, err = r.git("status", "--porcelain"); err != nil {
-		r.printer <- &msgInfo{
-			msg:   "skipped due to error: " + err.Error(),
-			row:   r.row,
-			col:   r.col,
-			color: red,
-			style: term.Bold,
+		r := &repo{
+			ctx:      ctx,
+			location: locs[info.path],
+			path:     info.path,
+			printer:  printer,
+			row:      i + 1,
+			col:      info.col,
+			heading:  info.heading,
+			result:   multirepo.Result{Path: info.path},
+			name:     displayName(root, info.path),
+			label:    info.label,
 		}
-		return
-	}
-	if out != "" {
-		r.printer <- &msgInfo{
-			msg:   "skipped due to changes",
-			row:   r.row,
-			col:   r.col,
-			color: magenta,
-			style: term.Bold,
+		repos = append(repos, r)
+		r.drawLabel()
+	}
+
+	if preflightCheck {
+		preflight(ctx, repos)
+	}
+
+	// Process the repos, limiting the number being worked on at once, both overall and for each host given a limit
+	var work chan *repo
+	var finished chan *repo
+	if len(hostLimits) == 0 {
+		work = make(chan *repo, len(repos))
+		for _, r := range repos {
+			work <- r
 		}
-		return
-	}
-	if out, err = r.git("pull"); err != nil {
-		r.printer <- &msgInfo{
-			msg:   "failed to pull: " + err.Error(),
-			row:   r.row,
-			col:   r.col,
-			color: red,
-			style: term.Bold,
+		close(work)
+	} else {
+		work = make(chan *repo)
+		finished = make(chan *repo, len(repos))
+		go scheduleByHost(repos, work, finished)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < min(jobs, len(repos)); i++ {
+		wg.Add(1)
+		go processRepos(&wg, work, finished, action)
+	}
+	wg.Wait()
+	close(printer)
+	printerWG.Wait()
+	repos = sortRepos(repos, root)
+	if len(deferred) != 0 {
+		printDeferred(repos, deferred)
+	}
+	if jsonOut {
+		emitJSON(repos)
+	} else {
+		if verbose {
+			printTranscripts(t, repos, root)
 		}
-		return
-	}
-	for _, s := range strings.Split(out, "\n") {
-		if strings.Contains(s, " changed, ") {
-			r.printer <- &msgInfo{
-				msg:   strings.TrimSpace(s),
-				row:   r.row,
-				col:   r.col,
-				color: magenta,
-				style: term.Bold,
-			}
-			return
+		printIncoming(t, repos, root)
+		printSummary(t, repos, root, time.Since(start))
+	}
+	if notify {
+		notifyResults(repos, root)
+	}
+	postWebhooks(repos, root, time.Since(start))
+	if recordingHistory() {
+		if err = saveHistory(repos, start); err != nil {
+			fmt.Fprintln(os.Stderr, multirepo.ErrorText(err))
 		}
 	}
-	r.printer <- &msgInfo{
-		msg:   "no changes",
-		row:   r.row,
-		col:   r.col,
-		color: blue,
-		style: term.Normal,
+	if reportPath != "" {
+		if err = writeReport(repos, root, start); err != nil {
+			fmt.Fprintln(os.Stderr, multirepo.ErrorText(err))
+		}
 	}
+	return repos, nil
 }
 
-func (r *repo) git(args ...string) (result string, err error) {
-	for i := 0; i < 5; i++ {
-		if i != 0 {
-			time.Sleep(time.Second)
-		}
-		result, err = r.gitActual(args...)
-		if err == nil {
-			return result, nil
-		}
-		r.printer <- &msgInfo{
-			msg:   fmt.Sprintf("retry #%d for %s", i+1, err.Error()),
-			row:   r.row,
-			col:   r.col,
-			color: magenta,
-			style: term.Bold,
+// defaultRoots returns the paths to search when none are specified: those from the configuration, or failing that, the
+// current directory. Returns nil if neither is available.
+func defaultRoots() []string {
+	paths := make([]string, 0, len(defaultPaths))
+	for _, p := range defaultPaths {
+		paths = append(paths, expandHome(p))
+	}
+	if len(paths) == 0 {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil
 		}
+		paths = append(paths, wd)
 	}
-	return result, err
+	return paths
 }
 
-func (r *repo) gitActual(args ...string) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-	c := exec.CommandContext(ctx, "git", args...)
-	c.Dir = r.path
-	c.Env = mergeEnvLists([]string{"PWD=" + r.path}, os.Environ())
-	rsp, err := c.CombinedOutput()
-	if err != nil {
-		return "", errs.NewWithCause(c.String(), err)
+// expandHome replaces a leading ~ in path with the user's home directory.
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return filepath.Join(paths.HomeDir(), path[1:])
 	}
-	return strings.TrimSpace(string(rsp)), nil
+	return path
 }
 
-func mergeEnvLists(in, out []string) []string {
-NextVar:
-	for _, ikv := range in {
-		k := strings.SplitAfterN(ikv, "=", 2)[0] + "="
-		for i, okv := range out {
-			if strings.HasPrefix(okv, k) {
-				out[i] = ikv
-				continue NextVar
+// exitStatus returns the status the process should exit with, given the results of processing repos.
+func exitStatus(repos []*repo) int {
+	status := 0
+	for _, r := range repos {
+		switch r.result.Outcome {
+		case multirepo.Failed, multirepo.Aborted, multirepo.TimedOut:
+			return exitFailed
+		case multirepo.Skipped:
+			if strict {
+				status = exitSkipped
 			}
+		default:
 		}
-		out = append(out, ikv)
 	}
-	return out
+	return status
 }
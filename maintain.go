@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/richardwilkes/toolbox/cmdline"
+)
+
+type maintainCmd struct{}
+
+var fullMaintenance bool
+
+func (c *maintainCmd) Name() string {
+	return "maintain"
+}
+
+func (c *maintainCmd) Usage() string {
+	return "Runs git's maintenance tasks, which repack objects, prune loose ones, and write the commit-graph, to " +
+		"keep the repos fast."
+}
+
+func (c *maintainCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	addCommonOptions(cl)
+	cl.NewGeneralOption(&fullMaintenance).SetName("full").
+		SetUsage("Run an aggressive garbage collection that recompresses all objects and prunes unreachable ones " +
+			"immediately, which is much slower")
+	run(cl.Parse(args), maintainRepo)
+	return nil
+}
+
+func maintainRepo(r *repo) {
+	if !r.showBranch() {
+		return
+	}
+	before, err := r.objectsSize()
+	if err != nil {
+		r.fail("error", err)
+		return
+	}
+	if fullMaintenance {
+		if _, err = r.git("gc", "--aggressive", "--prune=now", "--quiet"); err != nil {
+			r.fail("gc failed", err)
+			return
+		}
+	} else {
+		for _, task := range []string{"gc", "commit-graph"} {
+			if _, err = r.git("maintenance", "run", "--task="+task, "--quiet"); err != nil {
+				r.fail(task+" failed", err)
+				return
+			}
+		}
+	}
+	var after int64
+	if after, err = r.objectsSize(); err != nil {
+		r.fail("error", err)
+		return
+	}
+	if after < before {
+		r.changed(fmt.Sprintf("reduced from %s to %s", formatSize(before), formatSize(after)))
+	} else {
+		r.succeeded("maintained, " + formatSize(after))
+	}
+}
+
+// objectsSize returns the number of bytes used by the repo's objects, both loose and packed.
+func (r *repo) objectsSize() (int64, error) {
+	out, err := r.git("count-objects", "-v")
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok || (key != "size" && key != "size-pack") {
+			continue
+		}
+		if kib, parseErr := strconv.ParseInt(value, 10, 64); parseErr == nil {
+			size += kib * 1024
+		}
+	}
+	return size, nil
+}
+
+// formatSize returns size, a number of bytes, in the largest binary unit that leaves at least one whole unit.
+func formatSize(size int64) string {
+	const units = "KMGTPE"
+	if size < 1024 {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size) / 1024
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, units[i])
+}
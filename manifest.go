@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/errs"
+	"github.com/richardwilkes/toolbox/xio"
+	"github.com/richardwilkes/toolbox/xio/fs"
+	"gopkg.in/yaml.v3"
+)
+
+var manifest string
+
+// manifestEntry is a single repo listed in a manifest.
+type manifestEntry struct {
+	Path string `yaml:"path"`
+	URL  string `yaml:"url,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler, permitting an entry to be either a bare path or a mapping.
+func (e *manifestEntry) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		e.Path = node.Value
+		return nil
+	}
+	type plain manifestEntry
+	return node.Decode((*plain)(e))
+}
+
+// loadManifest returns the repos listed in the manifest file at path, in the same form as discover. Relative paths
+// within the manifest are resolved against the directory containing it, which also serves as the display root.
+func loadManifest(path string) (list []string, locs map[string]*location, root string, err error) {
+	var entries []manifestEntry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = fs.LoadYAML(path, &entries)
+	default:
+		entries, err = loadTextManifest(path)
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if root, err = multirepo.RealPath(filepath.Dir(path)); err != nil {
+		root = filepath.Dir(path)
+	}
+	var s *settings
+	if s, err = rootSettings(root); err != nil {
+		return nil, nil, "", err
+	}
+	locs = make(map[string]*location)
+	for _, entry := range entries {
+		if entry.Path == "" {
+			return nil, nil, "", errs.Newf("%s: entry with URL %q is missing a path", path, entry.URL)
+		}
+		p := expandHome(entry.Path)
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(root, p)
+		}
+		if isExcluded(s, root, p) || !isIncluded(root, p) {
+			continue
+		}
+		loc := &location{cfg: s, rel: multirepo.RelativePath(root, p), url: entry.URL}
+		if multirepo.IsRepo(p) {
+			if resolved, resolveErr := multirepo.RealPath(p); resolveErr == nil {
+				p = resolved
+			}
+		} else {
+			p = filepath.Clean(p)
+			loc.missing = true
+		}
+		if _, exists := locs[p]; !exists {
+			locs[p] = loc
+		}
+	}
+	return sortedPaths(locs), locs, root, nil
+}
+
+// loadTextManifest reads a plain text manifest, which has one repo per line in the form "path [url]". Blank lines and
+// lines starting with # are ignored.
+func loadTextManifest(path string) ([]manifestEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errs.NewWithCause(path, err)
+	}
+	defer xio.CloseIgnoringErrors(f)
+	var entries []manifestEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		entry := manifestEntry{Path: fields[0]}
+		if len(fields) > 1 {
+			entry.URL = fields[1]
+		}
+		entries = append(entries, entry)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errs.NewWithCause(path, err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+// refUpdateLine matches a line of fetch's output that reports a ref it changed, capturing the flag that describes the
+// kind of change.
+var refUpdateLine = regexp.MustCompile(`^ ([ +*t!-]) .*->\s+\S+`)
+
+// updateMirror updates the bare repo from all of its remotes, removing refs that no longer exist there, and reports the
+// refs that changed. With --show-log, the changes to each are listed once all repos have been processed.
+func updateMirror(r *repo) {
+	args := []string{"remote", "update", "--prune"}
+	if dryRun {
+		// remote update has no way to show what it would do, but fetch does and is otherwise equivalent
+		args = []string{"fetch", "--all", "--prune", "--dry-run"}
+	}
+	out, err := r.git(args...)
+	if err != nil {
+		r.fail("failed to update", err)
+		return
+	}
+	var updated, added, deleted, rejected int
+	for _, line := range strings.Split(out, "\n") {
+		m := refUpdateLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if showLog && !dryRun {
+			r.result.Commits = append(r.result.Commits, strings.TrimPrefix(line, " "))
+		}
+		switch m[1] {
+		case "*":
+			added++
+		case "-":
+			deleted++
+		case "!":
+			rejected++
+		default:
+			updated++
+		}
+	}
+	var parts []string
+	for _, one := range []struct {
+		count int
+		what  string
+	}{
+		{count: updated, what: "updated"},
+		{count: added, what: "new"},
+		{count: deleted, what: "deleted"},
+	} {
+		if one.count != 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", one.count, one.what))
+		}
+	}
+	switch {
+	case rejected != 0:
+		r.fail("failed to update", errs.Newf("%d %s rejected", rejected, plural(rejected, "ref", "refs")))
+	case len(parts) == 0:
+		r.succeeded("up to date")
+	case dryRun:
+		r.notice("would update refs: " + strings.Join(parts, ", "))
+	default:
+		r.changed("refs " + strings.Join(parts, ", "))
+	}
+}
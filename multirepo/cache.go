@@ -0,0 +1,99 @@
+package multirepo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scanRecord holds what a scan of one root found, along with what it examined to find it, so that it can be reused for
+// as long as none of that has changed.
+type scanRecord struct {
+	// Examined maps the directories read, and the .gpignore files consulted, to their modification times. Adding or
+	// removing anything within a directory changes its modification time.
+	Examined map[string]time.Time `json:"examined"`
+	Repos    []scannedRepo        `json:"repos"`
+}
+
+type scannedRepo struct {
+	Path string `json:"path"`
+	Rel  string `json:"rel"`
+	Bare bool   `json:"bare,omitempty"`
+}
+
+func newScanRecord() *scanRecord {
+	return &scanRecord{Examined: make(map[string]time.Time)}
+}
+
+// examine records the modification time of path, if it exists.
+func (rec *scanRecord) examine(path string) {
+	if fi, err := os.Stat(path); err == nil {
+		rec.Examined[path] = fi.ModTime()
+	}
+}
+
+// valid returns true if nothing the scan examined has changed since, and the repos it found are still there.
+func (rec *scanRecord) valid() bool {
+	for path, mtime := range rec.Examined {
+		fi, err := os.Stat(path)
+		if err != nil || !fi.ModTime().Equal(mtime) {
+			return false
+		}
+	}
+	for _, repo := range rec.Repos {
+		if repo.Bare && !IsBareRepo(repo.Path) || !repo.Bare && !IsRepo(repo.Path) {
+			return false
+		}
+	}
+	return true
+}
+
+// loadDiscoveryCache returns the scans saved in the file at path, keyed by scanKey. Problems reading the cache merely
+// make it empty, since the repos can always be found again.
+func loadDiscoveryCache(path string) map[string]*scanRecord {
+	cache := make(map[string]*scanRecord)
+	if data, err := os.ReadFile(path); err == nil {
+		if err = json.Unmarshal(data, &cache); err != nil {
+			return make(map[string]*scanRecord)
+		}
+	}
+	return cache
+}
+
+// saveDiscoveryCache saves the scans to the file at path for use by later runs. Failure to do so only costs those runs
+// time, so it isn't reported.
+func saveDiscoveryCache(path string, cache map[string]*scanRecord) {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	tmp := path + ".tmp" + strconv.Itoa(os.Getpid())
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		if removeErr := os.Remove(tmp); removeErr != nil {
+			return
+		}
+	}
+}
+
+// scanKey returns the key under which the scan of root is cached. It covers everything that affects which repos are
+// found, so that scans with different options don't use each other's results.
+func scanKey(root string, opts *DiscoverOptions) string {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	parts := []string{root, strconv.Itoa(opts.Depth), strings.Join(opts.Include, "\n"),
+		strings.Join(opts.Exclude, "\n"), strconv.FormatBool(opts.Bare)}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
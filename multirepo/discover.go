@@ -0,0 +1,218 @@
+package multirepo
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/richardwilkes/toolbox/errs"
+	"github.com/richardwilkes/toolbox/xio"
+)
+
+// DiscoverOptions controls which repos Discover finds.
+type DiscoverOptions struct {
+	// Depth limits how many levels below the root are searched. Zero means there is no limit.
+	Depth int
+	// Include, if not empty, limits the repos found to those matching one of these glob patterns.
+	Include []string
+	// Exclude holds glob patterns for directories to skip, along with everything within them.
+	Exclude []string
+	// CachePath, if set, is the file in which the results of scans are kept, so that later scans of roots in which
+	// nothing has changed can reuse them.
+	CachePath string
+	// Refresh forces a scan, even when a cached one is still valid.
+	Refresh bool
+	// Bare includes bare repos, such as mirrors, which have no checkout.
+	Bare bool
+}
+
+// Found describes a repo found by Discover.
+type Found struct {
+	// Path is the real path of the repo.
+	Path string
+	// Rel is the path of the repo relative to the root it was found within, using forward slashes.
+	Rel string
+	// Bare is true if the repo is a bare repo.
+	Bare bool
+}
+
+// Discover returns the git repos found within root. Once a git repo is found, its contents are not examined.
+// Directories whose names start with a dot are skipped, as is anything matching a pattern in a .gpignore file within
+// root. Patterns match either the base name of a directory or its path relative to the root.
+func Discover(root string, opts *DiscoverOptions) []Found {
+	if opts == nil {
+		opts = &DiscoverOptions{}
+	}
+	var cache map[string]*scanRecord
+	key := scanKey(root, opts)
+	if opts.CachePath != "" {
+		cache = loadDiscoveryCache(opts.CachePath)
+		if rec, ok := cache[key]; ok && !opts.Refresh && rec.valid() {
+			return resolveFound(rec.Repos)
+		}
+	}
+	rec := newScanRecord()
+	scan(root, root, opts.Depth, opts, nil, rec)
+	if opts.CachePath != "" {
+		cache[key] = rec
+		saveDiscoveryCache(opts.CachePath, cache)
+	}
+	return resolveFound(rec.Repos)
+}
+
+// scan looks for git repos within dir, descending at most depth levels. A depth less than 1 means there is no limit.
+// Repos are recorded in rec, along with the directories and files examined to find them. Anything matching the
+// .gpignore files of dir or the directories above it, as given by ignores, is skipped.
+func scan(root, dir string, depth int, opts *DiscoverOptions, ignores []*gpIgnore, rec *scanRecord) {
+	rec.examine(dir)
+	rec.examine(filepath.Join(dir, gpIgnoreName))
+	if ignore := loadGPIgnore(dir); ignore != nil {
+		ignores = append(slices.Clip(ignores), ignore)
+	}
+	for _, entry := range readDir(dir) {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			p := filepath.Join(dir, entry.Name())
+			if MatchesAny(opts.Exclude, root, p) || isGPIgnored(ignores, p) {
+				continue
+			}
+			if bare := opts.Bare && IsBareRepo(p); bare || IsRepo(p) {
+				if len(opts.Include) != 0 && !MatchesAny(opts.Include, root, p) {
+					continue
+				}
+				rec.Repos = append(rec.Repos, scannedRepo{Path: p, Rel: RelativePath(root, p), Bare: bare})
+				continue
+			}
+			if depth != 1 {
+				scan(root, p, depth-1, opts, ignores, rec)
+			} else {
+				// Making this a repo would change its modification time
+				rec.examine(p)
+			}
+		}
+	}
+}
+
+// RelativePath returns path relative to root, using forward slashes. Falls back to the base name of path should that
+// not be possible.
+func RelativePath(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return filepath.Base(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// MatchesAny returns true if the base name of path, or path relative to root, matches any of the glob patterns.
+func MatchesAny(patterns []string, root, path string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	name := filepath.Base(path)
+	rel := RelativePath(root, path)
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePatterns returns an error for the first of the glob patterns that is malformed.
+func ValidatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return errs.NewWithCause("invalid pattern: "+pattern, err)
+		}
+	}
+	return nil
+}
+
+// IsRepo returns true if path is the top of a git checkout. The .git entry may be either a directory or, for linked
+// worktrees and submodules, a file pointing to the actual git directory.
+func IsRepo(path string) bool {
+	gitPath := filepath.Join(path, ".git")
+	fi, err := os.Stat(gitPath)
+	if err != nil {
+		return false
+	}
+	if fi.IsDir() {
+		return true
+	}
+	return ResolveGitDir(gitPath) != ""
+}
+
+// IsBareRepo returns true if path is a bare git repo, which holds what would otherwise be in the .git directory of a
+// checkout, such as a mirror.
+func IsBareRepo(path string) bool {
+	for _, name := range []string{"objects", "refs"} {
+		if fi, err := os.Stat(filepath.Join(path, name)); err != nil || !fi.IsDir() {
+			return false
+		}
+	}
+	fi, err := os.Stat(filepath.Join(path, "HEAD"))
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// ResolveGitDir returns the git directory referenced by the "gitdir:" line of the .git file at path, or an empty string
+// if it cannot be resolved.
+func ResolveGitDir(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if dir, ok := strings.CutPrefix(strings.TrimSpace(line), "gitdir:"); ok {
+			if dir = strings.TrimSpace(dir); !filepath.IsAbs(dir) {
+				dir = filepath.Join(filepath.Dir(path), dir)
+			}
+			if fi, statErr := os.Stat(dir); statErr == nil && fi.IsDir() {
+				return dir
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// RealPath returns the absolute form of path with any symbolic links resolved. Unlike resolving the components
+// separately, this handles Windows drive letters and UNC paths.
+func RealPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	var resolved string
+	if resolved, err = filepath.EvalSymlinks(abs); err != nil {
+		return "", errs.Wrap(err)
+	}
+	return resolved, nil
+}
+
+// resolveFound returns the repos a scan recorded, with their real paths. Those that can no longer be resolved are
+// omitted.
+func resolveFound(repos []scannedRepo) []Found {
+	found := make([]Found, 0, len(repos))
+	for _, repo := range repos {
+		if p, err := RealPath(repo.Path); err == nil {
+			found = append(found, Found{Path: p, Rel: repo.Rel, Bare: repo.Bare})
+		}
+	}
+	return found
+}
+
+func readDir(path string) []os.DirEntry {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer xio.CloseIgnoringErrors(f)
+	var entries []os.DirEntry
+	if entries, err = f.ReadDir(-1); err != nil {
+		return nil
+	}
+	return entries
+}
@@ -0,0 +1,4 @@
+// Package multirepo provides the engine behind gp for working with many git repos at once: finding them beneath a set
+// of directories, running git within them with timeouts, retries of transient failures, and classification of the
+// failures that remain, and describing the outcome of each in a form suitable for reporting.
+package multirepo
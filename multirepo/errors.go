@@ -0,0 +1,25 @@
+package multirepo
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+// ErrorText returns the message of err, along with those of its causes, without any stack traces.
+func ErrorText(err error) string {
+	var parts []string
+	for err != nil {
+		se, ok := err.(errs.StackError)
+		if !ok {
+			parts = append(parts, err.Error())
+			break
+		}
+		if msg := se.Message(); msg != "" && (len(parts) == 0 || parts[len(parts)-1] != msg) {
+			parts = append(parts, msg)
+		}
+		err = errors.Unwrap(err)
+	}
+	return strings.Join(parts, ": ")
+}
@@ -0,0 +1,131 @@
+package multirepo
+
+import (
+	"strings"
+)
+
+// AuthenticationDenied is the kind of failure given to errors from git commands whose credentials were refused or
+// couldn't be obtained.
+const AuthenticationDenied = "authentication denied"
+
+// failureKind describes one of the reasons git commands commonly fail.
+type failureKind struct {
+	kind      string
+	markers   []string // fragments of git's output that identify the failure, in lowercase
+	transient bool     // true if the failure may go away by itself, and so is worth retrying
+}
+
+// failureKinds are the reasons git commands commonly fail. They are checked in order, as some output contains fragments
+// of more than one, such as an authentication failure followed by a note that the connection was closed.
+var failureKinds = []failureKind{
+	{
+		kind: AuthenticationDenied,
+		markers: []string{
+			"authentication failed",
+			"permission denied",
+			"could not read username",
+			"could not read password",
+			"invalid username or password",
+			"host key verification failed",
+			"the requested url returned error: 401",
+			"the requested url returned error: 403",
+		},
+	},
+	{
+		kind: "host unreachable",
+		markers: []string{
+			"could not resolve host",
+			"could not resolve hostname",
+			"connection timed out",
+			"connection refused",
+			"operation timed out",
+			"network is unreachable",
+			"no route to host",
+			"failed to connect",
+			"could not connect",
+			"no such host",
+			"i/o timeout",
+		},
+		transient: true,
+	},
+	{
+		kind: "connection lost",
+		markers: []string{
+			"connection reset",
+			"connection closed",
+			"temporary failure",
+			"the remote end hung up unexpectedly",
+			"early eof",
+			"rpc failed",
+			"gnutls",
+			"ssl_read",
+			"tls handshake",
+			"the requested url returned error: 5",
+		},
+		transient: true,
+	},
+	{
+		kind: "lock held",
+		markers: []string{
+			"index.lock",
+			"another git process seems to be running",
+			"cannot lock ref",
+			"unable to create '",
+		},
+		transient: true,
+	},
+	{
+		kind: "merge conflict",
+		markers: []string{
+			"conflict (",
+			"automatic merge failed",
+			"could not apply",
+			"would be overwritten by merge",
+			"resolve all conflicts",
+		},
+	},
+	{
+		kind: "diverged history",
+		markers: []string{
+			"not possible to fast-forward",
+			"divergent branches",
+			"have diverged",
+			"non-fast-forward",
+		},
+	},
+}
+
+// ClassifiedError is an error from a git command for which the reason it failed could be determined from its output.
+type ClassifiedError struct {
+	error
+	Kind string // a short description of the reason, such as "merge conflict"
+}
+
+func (e *ClassifiedError) Error() string {
+	return ErrorText(e.error)
+}
+
+func (e *ClassifiedError) Unwrap() error {
+	return e.error
+}
+
+// Classify returns err annotated with the kind of failure indicated by output, if that can be determined.
+func Classify(err error, output string) error {
+	if fk := classify(output); fk != nil {
+		return &ClassifiedError{error: err, Kind: fk.kind}
+	}
+	return err
+}
+
+// classify returns the kind of failure indicated by output, or nil if that can't be determined.
+func classify(output string) *failureKind {
+	output = strings.ToLower(output)
+	for i := range failureKinds {
+		for _, marker := range failureKinds[i].markers {
+			if strings.Contains(output, marker) {
+				return &failureKinds[i]
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package multirepo_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/check"
+)
+
+func TestClassify(t *testing.T) {
+	failed := errors.New("exit status 1")
+	for i, one := range []struct {
+		output string
+		kind   string
+	}{
+		{output: "remote: Invalid username or password.", kind: "authentication denied"},
+		{output: "error: The requested URL returned error: 403", kind: "authentication denied"},
+		{output: "Host key verification failed.", kind: "authentication denied"},
+		{output: "ssh: Could not resolve hostname example.com: Name or service not known", kind: "host unreachable"},
+		{output: "dial tcp: lookup example.com: no such host", kind: "host unreachable"},
+		{output: "fatal: the remote end hung up unexpectedly\nfatal: early EOF", kind: "connection lost"},
+		{
+			output: "error: RPC failed; curl 18 transfer closed with outstanding read data remaining",
+			kind:   "connection lost",
+		},
+		{output: "fatal: Unable to create '/src/x/.git/index.lock': File exists.", kind: "lock held"},
+		{output: "CONFLICT (content): Merge conflict in main.go", kind: "merge conflict"},
+		{
+			output: "error: Your local changes to the following files would be overwritten by merge:",
+			kind:   "merge conflict",
+		},
+		{output: "fatal: Not possible to fast-forward, aborting.", kind: "diverged history"},
+		{output: " ! [rejected]        main -> main (non-fast-forward)", kind: "diverged history"},
+		// Where the output holds the markers of more than one kind, the earlier in failureKinds wins
+		{
+			output: "ssh: connect to host example.com port 22: Connection refused\nPermission denied (publickey).",
+			kind:   "authentication denied",
+		},
+		{
+			output: "fatal: could not read Username for 'https://example.com': terminal prompts disabled\n" +
+				"failed to connect",
+			kind: "authentication denied",
+		},
+		{
+			output: "Another git process seems to be running in this repository\nresolve all conflicts first",
+			kind:   "lock held",
+		},
+		{output: "Automatic merge failed\nhint: You have divergent branches", kind: "merge conflict"},
+		{output: "fatal: 'origin' does not appear to be a git repository"},
+		{output: ""},
+	} {
+		err := multirepo.Classify(failed, one.output)
+		check.True(t, errors.Is(err, failed), "case %d: the original error must be retained", i)
+		var classified *multirepo.ClassifiedError
+		if one.kind == "" {
+			check.False(t, errors.As(err, &classified), "case %d: %q should not be classified", i, one.output)
+			continue
+		}
+		check.True(t, errors.As(err, &classified), "case %d: %q should be classified", i, one.output)
+		if classified != nil {
+			check.Equal(t, one.kind, classified.Kind, "case %d: %q", i, one.output)
+		}
+	}
+}
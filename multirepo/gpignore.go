@@ -0,0 +1,51 @@
+package multirepo
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/richardwilkes/toolbox/xio"
+)
+
+const gpIgnoreName = ".gpignore"
+
+// gpIgnore holds the patterns from a .gpignore file, which name repos within its directory that should be skipped.
+type gpIgnore struct {
+	dir      string
+	patterns []string
+}
+
+// loadGPIgnore loads the .gpignore file in dir. Returns nil if there isn't one or it has no patterns. Blank lines and
+// lines starting with # are ignored.
+func loadGPIgnore(dir string) *gpIgnore {
+	f, err := os.Open(filepath.Join(dir, gpIgnoreName))
+	if err != nil {
+		return nil
+	}
+	defer xio.CloseIgnoringErrors(f)
+	ignore := &gpIgnore{dir: dir}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ignore.patterns = append(ignore.patterns, strings.TrimSuffix(line, "/"))
+	}
+	if len(ignore.patterns) == 0 {
+		return nil
+	}
+	return ignore
+}
+
+// isGPIgnored returns true if path matches the patterns of any of the .gpignore files, relative to their directories.
+func isGPIgnored(ignores []*gpIgnore, path string) bool {
+	for _, ignore := range ignores {
+		if MatchesAny(ignore.patterns, ignore.dir, path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package multirepo
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// progressPattern matches the progress lines git writes to stderr when given --progress, such as "Receiving objects:
+// 42% (420/1000)", including those relayed from the remote.
+var progressPattern = regexp.MustCompile(`^(?:remote: )?([A-Za-z][A-Za-z ]*):\s+(\d{1,3})%`)
+
+// ProgressWriter separates git's progress reporting from the rest of its output. Progress is passed to a callback as it
+// arrives, while everything else is retained.
+type ProgressWriter struct {
+	lock       sync.Mutex
+	onProgress func(phase string, percent int)
+	pending    []byte
+	out        bytes.Buffer
+	afterCR    bool
+}
+
+// NewProgressWriter creates a new ProgressWriter that calls onProgress with the phase and percentage of each progress
+// line written to it.
+func NewProgressWriter(onProgress func(phase string, percent int)) *ProgressWriter {
+	return &ProgressWriter{onProgress: onProgress}
+}
+
+// Write implements io.Writer. Git ends progress lines with a carriage return while the phase is underway, so both that
+// and a newline end a line, as does the pair of them.
+func (w *ProgressWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexAny(w.pending, "\r\n")
+		if i == -1 {
+			break
+		}
+		if i != 0 || w.pending[i] != '\n' || !w.afterCR {
+			w.consume(w.pending[:i], w.pending[i] == '\n')
+		}
+		// A line ended with the pair has already been dealt with by the carriage return, unless it was empty
+		w.afterCR = w.pending[i] == '\r' && i != 0
+		w.pending = w.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *ProgressWriter) consume(line []byte, newline bool) {
+	if m := progressPattern.FindSubmatch(line); m != nil {
+		if percent, err := strconv.Atoi(string(m[2])); err == nil {
+			w.onProgress(string(m[1]), percent)
+			return
+		}
+	}
+	if newline || len(bytes.TrimSpace(line)) != 0 {
+		w.out.Write(line)
+		w.out.WriteByte('\n')
+	}
+}
+
+// Output returns everything written that wasn't progress.
+func (w *ProgressWriter) Output() []byte {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if len(w.pending) != 0 {
+		w.consume(w.pending, true)
+		w.pending = nil
+	}
+	return w.out.Bytes()
+}
@@ -0,0 +1,72 @@
+package multirepo_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/check"
+)
+
+func TestProgressWriter(t *testing.T) {
+	for i, one := range []struct {
+		writes   []string
+		progress []string
+		output   string
+	}{
+		{
+			writes:   []string{"Receiving objects:  42% (420/1000)\rReceiving objects: 100% (1000/1000), done.\n"},
+			progress: []string{"Receiving objects 42", "Receiving objects 100"},
+		},
+		{
+			writes: []string{
+				"remote: Counting objects:  50% (1/2)\r",
+				"remote: Counting objects: 100% (2/2), done.\r\n",
+			},
+			progress: []string{"Counting objects 50", "Counting objects 100"},
+		},
+		{
+			// Lines may be split across writes
+			writes: []string{
+				"Resolving del", "tas:   7% (1/14)", "\rFrom example.com:x\n", " * branch main -> FETCH_HEAD\n",
+			},
+			progress: []string{"Resolving deltas 7"},
+			output:   "From example.com:x\n * branch main -> FETCH_HEAD\n",
+		},
+		{
+			// The CR that git uses to return to the start of a line doesn't produce an empty line of output
+			writes: []string{"Already up to date.\r\n\r\n"},
+			output: "Already up to date.\n\n",
+		},
+		{
+			writes: []string{"warning: one\r", "\nwarning: two\r\n"},
+			output: "warning: one\nwarning: two\n",
+		},
+		{
+			// A final line without an ending still appears in the output
+			writes: []string{"error: cannot pull\nfatal: no ending"},
+			output: "error: cannot pull\nfatal: no ending\n",
+		},
+		{
+			writes: []string{"Updating 1234567..89abcde\nFast-forward\n main.go | 2 +-\n"},
+			output: "Updating 1234567..89abcde\nFast-forward\n main.go | 2 +-\n",
+		},
+		{
+			// Percentages must lead the text after the phase to be taken for progress
+			writes: []string{"note: about 50% of the way\n"},
+			output: "note: about 50% of the way\n",
+		},
+	} {
+		var progress []string
+		w := multirepo.NewProgressWriter(func(phase string, percent int) {
+			progress = append(progress, fmt.Sprintf("%s %d", phase, percent))
+		})
+		for _, s := range one.writes {
+			n, err := w.Write([]byte(s))
+			check.NoError(t, err, "case %d", i)
+			check.Equal(t, len(s), n, "case %d", i)
+		}
+		check.Equal(t, one.output, string(w.Output()), "case %d", i)
+		check.Equal(t, one.progress, progress, "case %d", i)
+	}
+}
@@ -0,0 +1,91 @@
+package multirepo
+
+import (
+	"time"
+
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+// Outcome is the overall result of processing a repo.
+type Outcome int
+
+// The possible outcomes.
+const (
+	Unchanged Outcome = iota
+	Updated
+	Skipped
+	Failed
+	Aborted
+	TimedOut
+	Ignored
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Updated:
+		return "updated"
+	case Skipped:
+		return "skipped"
+	case Failed:
+		return "failed"
+	case Aborted:
+		return "aborted"
+	case TimedOut:
+		return "timed out"
+	case Ignored:
+		return "ignored"
+	default:
+		return "unchanged"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (o Outcome) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (o *Outcome) UnmarshalText(text []byte) error {
+	for one := Unchanged; one <= Ignored; one++ {
+		if one.String() == string(text) {
+			*o = one
+			return nil
+		}
+	}
+	return errs.New("unknown outcome: " + string(text))
+}
+
+// CommitInfo describes a commit.
+type CommitInfo struct {
+	Time    time.Time `json:"time"`
+	Author  string    `json:"author"`
+	Subject string    `json:"subject"`
+}
+
+// Result holds the final state of a repo once it has been processed.
+type Result struct {
+	Path         string            `json:"path"`
+	Branch       string            `json:"branch,omitempty"`
+	Ahead        int               `json:"ahead,omitempty"`
+	Behind       int               `json:"behind,omitempty"`
+	Stashes      int               `json:"stashes,omitempty"`
+	Stale        int               `json:"stale_stashes,omitempty"` // the stashes older than the stale_stash setting
+	LastCommit   *CommitInfo       `json:"last_commit,omitempty"`
+	StaleHead    bool              `json:"stale,omitempty"` // true if the last commit is older than the stale setting
+	WorktreeSize int64             `json:"worktree_bytes,omitempty"`
+	GitSize      int64             `json:"git_bytes,omitempty"` // includes LFSSize
+	LFSSize      int64             `json:"lfs_bytes,omitempty"`
+	Remotes      map[string]string `json:"remotes,omitempty"` // the URL of each remote, by name
+	Email        string            `json:"email,omitempty"`   // the user.email in effect
+	Signed       bool              `json:"signed,omitempty"`  // true if commits are configured to be signed
+	Head         string            `json:"head,omitempty"`    // the commit checked out once processed, for the history
+	Outcome      Outcome           `json:"outcome"`
+	Status       string            `json:"status"`
+	Changes      string            `json:"changes,omitempty"`
+	Error        string            `json:"error,omitempty"`
+	Reason       string            `json:"reason,omitempty"` // the kind of failure, when it could be determined
+	Warnings     []string          `json:"warnings,omitempty"`
+	Commits      []string          `json:"commits,omitempty"`
+	Diffstat     []string          `json:"diffstat,omitempty"`
+	Duration     float64           `json:"duration"` // in seconds
+}
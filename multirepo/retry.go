@@ -0,0 +1,35 @@
+package multirepo
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// maxBackoff limits how long a single wait between retries may be.
+const maxBackoff = time.Minute
+
+// IsTransient returns true if err, along with the output of the command that produced it, indicates a failure that
+// may go away by itself, such as a network problem or a lock held by another git process. Anything else, such as a
+// merge conflict or an authentication failure, is not worth retrying.
+func IsTransient(err error, output string) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	fk := classify(output)
+	return fk != nil && fk.transient
+}
+
+// Backoff returns how long to wait before the given retry attempt, starting with delay and doubling for each
+// subsequent attempt. Up to 50% random jitter is added so that concurrent retries against the same server spread out.
+func Backoff(delay time.Duration, attempt int) time.Duration {
+	if delay <= 0 {
+		return 0
+	}
+	for i := 1; i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxBackoff)
+	return delay + rand.N(delay/2+1)
+}
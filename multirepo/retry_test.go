@@ -0,0 +1,80 @@
+package multirepo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/check"
+)
+
+func TestIsTransient(t *testing.T) {
+	failed := errors.New("exit status 128")
+	for i, one := range []struct {
+		err      error
+		output   string
+		expected bool
+	}{
+		{
+			err:      failed,
+			output:   "fatal: unable to access 'https://example.com/x.git/': Could not resolve host: example.com",
+			expected: true,
+		},
+		{err: failed, output: "ssh: connect to host example.com port 22: Connection refused", expected: true},
+		{err: failed, output: "fatal: the remote end hung up unexpectedly", expected: true},
+		{err: failed, output: "error: RPC failed; curl 56 GnuTLS recv error (-9)", expected: true},
+		{err: failed, output: "error: The requested URL returned error: 503", expected: true},
+		{err: failed, output: "fatal: Unable to create '/src/x/.git/index.lock': File exists.", expected: true},
+		{err: failed, output: "error: cannot lock ref 'refs/remotes/origin/main'", expected: true},
+		{err: fmt.Errorf("wrapped: %w", context.DeadlineExceeded), expected: true},
+		{err: failed, output: "CONNECTION TIMED OUT", expected: true},
+		// Every kind of failure that Classify considers a network problem is retried
+		{err: failed, output: "ssh: connect to host example.com port 22: No route to host", expected: true},
+		{err: failed, output: "curl: (7) Failed to connect to example.com port 443", expected: true},
+		{err: failed, output: "dial tcp: lookup example.com: no such host", expected: true},
+		{
+			err: failed,
+			output: "remote: Invalid username or password.\n" +
+				"fatal: Authentication failed for 'https://example.com/x.git/'",
+		},
+		{
+			err: failed,
+			output: "git@example.com: Permission denied (publickey).\n" +
+				"fatal: Could not read from remote repository.\nConnection closed",
+		},
+		{err: failed, output: "error: The requested URL returned error: 404"},
+		{err: failed, output: "CONFLICT (content): Merge conflict in main.go\nAutomatic merge failed"},
+		{err: failed, output: "fatal: Not possible to fast-forward, aborting."},
+		{err: failed},
+	} {
+		check.Equal(t, one.expected, multirepo.IsTransient(one.err, one.output), "case %d: %q", i, one.output)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for i, one := range []struct {
+		delay   time.Duration
+		attempt int
+		base    time.Duration
+	}{
+		{delay: 0, attempt: 1, base: 0},
+		{delay: -time.Second, attempt: 3, base: 0},
+		{delay: time.Second, attempt: 0, base: time.Second},
+		{delay: time.Second, attempt: 1, base: time.Second},
+		{delay: time.Second, attempt: 2, base: 2 * time.Second},
+		{delay: time.Second, attempt: 4, base: 8 * time.Second},
+		{delay: time.Second, attempt: 7, base: time.Minute},
+		{delay: time.Second, attempt: 100, base: time.Minute},
+		{delay: 2 * time.Minute, attempt: 1, base: time.Minute},
+	} {
+		// The jitter is random, so check that every wait falls within the range it allows
+		for range 100 {
+			wait := multirepo.Backoff(one.delay, one.attempt)
+			check.True(t, wait >= one.base && wait <= one.base+one.base/2, "case %d: %v not within [%v, %v]", i, wait,
+				one.base, one.base+one.base/2)
+		}
+	}
+}
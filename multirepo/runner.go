@@ -0,0 +1,168 @@
+package multirepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+// Runner runs commands within a single directory, normally that of a repo.
+type Runner struct {
+	// Dir is the directory the commands are run in.
+	Dir string
+	// Env holds environment variables, in the form "key=value", that take precedence over those of the process.
+	Env []string
+	// Timeout limits how long each command may run. Zero means no limit.
+	Timeout time.Duration
+	// Retries is the number of times Git retries a command whose failure looks transient.
+	Retries int
+	// RetryDelay is the initial delay before a retry, which doubles for each subsequent one.
+	RetryDelay time.Duration
+	// Intercept, if set, is given the chance to perform each git command some other way, such as in-process. It
+	// returns handled as false for those it leaves to git.
+	Intercept func(args []string) (out string, handled bool, err error)
+	// OnCommand, if set, is called once each command that was started has completed, with its combined output.
+	OnCommand func(c *exec.Cmd, start time.Time, out string, err error)
+	// OnRetry, if set, is called while waiting to retry, initially and then once a second, with the number of the
+	// attempt about to be made, the number that may be made in all, the time remaining before it is, and the error that
+	// prompted it, as a *ClassifiedError if its reason could be determined.
+	OnRetry func(attempt, attempts int, remaining time.Duration, err error)
+	// OnProgress, if set, is called with the phase and percentage of each progress line a command writes, such as
+	// those git writes when given --progress. Progress lines are omitted from the output returned.
+	OnProgress func(phase string, percent int)
+}
+
+// Git runs git with args, retrying failures that look transient. Failures whose reason can be determined from git's
+// output are returned as a *ClassifiedError. Should every attempt fail, the failure returned is the most informative
+// of them, rather than simply the last, which is often just a timeout.
+func (r *Runner) Git(ctx context.Context, args ...string) (result string, err error) {
+	defer func() {
+		if err != nil {
+			err = Classify(err, result)
+		}
+	}()
+	var bestResult string
+	var bestErr error
+	for i := 0; i <= r.Retries; i++ {
+		if i != 0 && !r.waitToRetry(ctx, i, Classify(err, result)) {
+			return result, err
+		}
+		result, err = r.GitOnce(ctx, args...)
+		if err == nil || ctx.Err() != nil || !IsTransient(err, result) {
+			return result, err
+		}
+		if bestErr == nil || informativeness(err, result) >= informativeness(bestErr, bestResult) {
+			bestResult = result
+			bestErr = err
+		}
+	}
+	return bestResult, bestErr
+}
+
+// waitToRetry waits before the given retry attempt, reporting the time remaining to OnRetry. Returns false if ctx was
+// cancelled while waiting.
+func (r *Runner) waitToRetry(ctx context.Context, attempt int, err error) bool {
+	deadline := time.Now().Add(Backoff(r.RetryDelay, attempt))
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return true
+		}
+		if r.OnRetry != nil {
+			r.OnRetry(attempt+1, r.Retries+1, remaining, err)
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(remaining):
+			return true
+		case <-ticker.C:
+		}
+	}
+}
+
+// informativeness ranks how much a failure says about its cause: those whose reason can be determined rank highest,
+// and timeouts, which say nothing beyond that the command took too long, rank lowest.
+func informativeness(err error, output string) int {
+	var ce *ClassifiedError
+	switch {
+	case errors.As(Classify(err, output), &ce):
+		return 2
+	case errors.Is(err, context.DeadlineExceeded):
+		return 0
+	default:
+		return 1
+	}
+}
+
+// GitOnce runs git with args, without retrying.
+func (r *Runner) GitOnce(ctx context.Context, args ...string) (string, error) {
+	if r.Intercept != nil {
+		if out, handled, err := r.Intercept(args); handled {
+			return out, err
+		}
+	}
+	return r.Run(ctx, "git", args...)
+}
+
+// Run executes the named program with args, returning its combined output, even when it fails.
+func (r *Runner) Run(ctx context.Context, name string, args ...string) (string, error) {
+	cmdCtx := ctx
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		cmdCtx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	c := exec.CommandContext(cmdCtx, name, args...)
+	c.WaitDelay = time.Second
+	c.Dir = r.Dir
+	c.Env = MergeEnv(append([]string{"PWD=" + c.Dir}, r.Env...), os.Environ())
+	start := time.Now()
+	var rsp []byte
+	var err error
+	if r.OnProgress != nil {
+		w := NewProgressWriter(r.OnProgress)
+		c.Stdout = w
+		c.Stderr = w
+		err = c.Run()
+		rsp = w.Output()
+	} else {
+		rsp, err = c.CombinedOutput()
+	}
+	out := strings.TrimSpace(string(rsp))
+	if r.OnCommand != nil {
+		r.OnCommand(c, start, out, err)
+	}
+	if err != nil {
+		if cmdCtx.Err() != nil && ctx.Err() == nil {
+			return out, errs.NewWithCause(fmt.Sprintf("%s timed out after %v", c.String(), r.Timeout), cmdCtx.Err())
+		}
+		return out, errs.NewWithCause(c.String(), err)
+	}
+	return out, nil
+}
+
+// MergeEnv returns the environment variables of out, in the form "key=value", with those of in replacing any with the
+// same key and added otherwise.
+func MergeEnv(in, out []string) []string {
+NextVar:
+	for _, ikv := range in {
+		k := strings.SplitAfterN(ikv, "=", 2)[0] + "="
+		for i, okv := range out {
+			if strings.HasPrefix(okv, k) {
+				out[i] = ikv
+				continue NextVar
+			}
+		}
+		out = append(out, ikv)
+	}
+	return out
+}
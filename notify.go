@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+var notify bool
+
+// windowsToastScript displays a toast notification using the WinRT APIs available to PowerShell. The title and message
+// are passed through the environment to avoid any quoting issues.
+const windowsToastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$kind = [Windows.UI.Notifications.ToastTemplateType]::ToastText02
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent($kind)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode($env:GP_NOTIFY_TITLE)) > $null
+$text.Item(1).AppendChild($template.CreateTextNode($env:GP_NOTIFY_MESSAGE)) > $null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($template)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('gp').Show($toast)`
+
+// notifyResults sends a desktop notification listing the repos that were updated or failed, if there were any.
+func notifyResults(repos []*repo, root string) {
+	var updatedNames, failedNames []string
+	for _, r := range repos {
+		switch r.result.Outcome {
+		case multirepo.Updated:
+			updatedNames = append(updatedNames, displayName(root, r.path))
+		case multirepo.Failed, multirepo.TimedOut:
+			failedNames = append(failedNames, displayName(root, r.path))
+		default:
+		}
+	}
+	var lines []string
+	if len(updatedNames) != 0 {
+		lines = append(lines, "Updated: "+strings.Join(updatedNames, ", "))
+	}
+	if len(failedNames) != 0 {
+		lines = append(lines, "Failed: "+strings.Join(failedNames, ", "))
+	}
+	if len(lines) == 0 {
+		return
+	}
+	if err := sendNotification("gp", strings.Join(lines, "\n")); err != nil {
+		fmt.Fprintln(os.Stderr, multirepo.ErrorText(err))
+	}
+}
+
+// sendNotification displays a desktop notification using the platform's native facility.
+func sendNotification(title, message string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var c *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		c = exec.CommandContext(ctx, "osascript", "-e", fmt.Sprintf("display notification %s with title %s",
+			appleScriptString(message), appleScriptString(title)))
+	case "windows":
+		c = exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", windowsToastScript)
+		c.Env = append(os.Environ(), "GP_NOTIFY_TITLE="+title, "GP_NOTIFY_MESSAGE="+message)
+	default:
+		c = exec.CommandContext(ctx, "notify-send", title, message)
+	}
+	if out, err := c.CombinedOutput(); err != nil {
+		msg := "unable to send notification"
+		if detail := strings.TrimSpace(string(out)); detail != "" {
+			msg += ": " + detail
+		}
+		return errs.NewWithCause(msg, err)
+	}
+	return nil
+}
+
+// appleScriptString returns s as a quoted AppleScript string literal.
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
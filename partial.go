@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/richardwilkes/toolbox/cmdline"
+)
+
+const partialFilter = "blob:none"
+
+type partialCmd struct {
+	convert bool
+	minSize int
+}
+
+func (c *partialCmd) Name() string {
+	return "partial"
+}
+
+func (c *partialCmd) Usage() string {
+	return "Reports the repos that could shrink by becoming partial clones, which fetch file contents only when they " +
+		"are needed, and optionally converts them."
+}
+
+func (c *partialCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	addCommonOptions(cl)
+	c.minSize = 100
+	cl.NewGeneralOption(&c.convert).SetName("convert").
+		SetUsage("Convert the candidates into partial clones, configuring origin to omit file contents and removing " +
+			"every one already present, so that they are fetched from origin again when needed. Repos with local " +
+			"changes, stashes, or commits reachable from anything but origin's remote-tracking branches, such as " +
+			"local branches, tags, or other remotes, are left alone, since their contents couldn't be fetched again. " +
+			"Requires git 2.43 or later")
+	cl.NewGeneralOption(&c.minSize).SetName("min-size").SetArg("MiB").
+		SetUsage("Only consider repos whose objects take up at least this much space")
+	paths := cl.Parse(args)
+	// Without converting, the repos are only inspected
+	locking = c.convert
+	recording = c.convert
+	if c.convert && !gitVersionAtLeast(2, 43) {
+		cl.FatalMsg("--convert requires git 2.43 or later")
+	}
+	run(paths, c.partialRepo)
+	return nil
+}
+
+func (c *partialCmd) partialRepo(r *repo) {
+	if !r.showBranch() {
+		return
+	}
+	if filter, err := r.gitActual("config", "--get", "remote.origin.partialclonefilter"); err == nil && filter != "" {
+		r.succeeded("already a partial clone (" + filter + ")")
+		return
+	}
+	if _, err := r.gitActual("remote", "get-url", "origin"); err != nil {
+		r.skipState("no origin remote")
+		return
+	}
+	size, err := r.objectsSize()
+	if err != nil {
+		r.fail("error", err)
+		return
+	}
+	if size < int64(c.minSize)<<20 {
+		r.succeeded("not worth converting, " + formatSize(size))
+		return
+	}
+	if !c.convert {
+		r.notice("candidate, " + formatSize(size) + " of objects")
+		return
+	}
+	var local changes
+	if local, err = r.localChanges(); err != nil {
+		r.fail("error", err)
+		return
+	}
+	if local.total() != 0 {
+		r.skip("local changes")
+		return
+	}
+	var out string
+	if out, err = r.git("stash", "list"); err != nil {
+		r.fail("error", err)
+		return
+	}
+	if out != "" {
+		r.skip("stashes")
+		return
+	}
+	// Anything reachable from other than origin's refs, whether local branches and tags or other remotes, would lose
+	// contents that origin can't supply again
+	if out, err = r.git("rev-list", "--count", "--all", "--not", "--remotes=origin"); err != nil {
+		r.fail("error", err)
+		return
+	}
+	if out != "0" {
+		r.skip("commits not on origin")
+		return
+	}
+	for _, kv := range [][2]string{
+		{"remote.origin.promisor", "true"},
+		{"remote.origin.partialclonefilter", partialFilter},
+	} {
+		if _, err = r.git("config", kv[0], kv[1]); err != nil {
+			r.fail("unable to configure origin", err)
+			return
+		}
+	}
+	if _, err = r.git("repack", "-a", "-d", "--filter="+partialFilter); err != nil {
+		r.fail("repack failed", err)
+		return
+	}
+	var after int64
+	if after, err = r.objectsSize(); err != nil {
+		r.fail("error", err)
+		return
+	}
+	r.changed(fmt.Sprintf("converted, reduced from %s to %s", formatSize(size), formatSize(after)))
+}
+
+// gitVersionAtLeast returns true if the installed git is at least the given version.
+func gitVersionAtLeast(major, minor int) bool {
+	out, err := exec.Command("git", "version").Output()
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) < 3 {
+		return false
+	}
+	parts := strings.Split(fields[2], ".")
+	if len(parts) < 2 {
+		return false
+	}
+	gotMajor, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	var gotMinor int
+	if gotMinor, err = strconv.Atoi(parts[1]); err != nil {
+		return false
+	}
+	return gotMajor > major || (gotMajor == major && gotMinor >= minor)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/errs"
+	"github.com/richardwilkes/toolbox/xio"
+)
+
+// preflightTimeout limits how long the check of each host may take.
+const preflightTimeout = 5 * time.Second
+
+var preflightCheck bool
+
+// endpoint identifies where a remote is reached.
+type endpoint struct {
+	ssh  bool
+	host string // for SSH, as given to ssh, which may include the user and be an alias from its configuration
+	port string // empty for the default
+}
+
+// preflight checks, once for each distinct host the repos' origins are on, that the host can be reached, marking the
+// repos whose host can't be so that they fail at once rather than each waiting out their own timeouts and retries.
+// Hosts reached through a proxy aren't checked, as only the proxy could be.
+func preflight(ctx context.Context, repos []*repo) {
+	byEndpoint := make(map[endpoint][]*repo)
+	for _, r := range repos {
+		if ep, ok := remoteEndpoint(r.reachedURL()); ok {
+			byEndpoint[ep] = append(byEndpoint[ep], r)
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, preflightTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for ep, list := range byEndpoint {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := checkEndpoint(ctx, ep); err != nil {
+				for _, r := range list {
+					r.unreachable = err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// remoteEndpoint returns where the remote at u, a git remote URL, is reached. Returns false for local paths, for
+// protocols that aren't checked, and for hosts reached through a proxy.
+func remoteEndpoint(u string) (endpoint, bool) {
+	u = strings.TrimSpace(u)
+	if host := sshHost(u); host != "" {
+		if !strings.Contains(u, "://") {
+			// scp-like syntax, e.g. user@host:path
+			userHost, _, _ := strings.Cut(u, ":")
+			return endpoint{ssh: true, host: userHost}, true
+		}
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return endpoint{}, false
+		}
+		userHost := parsed.Hostname()
+		if parsed.User != nil && parsed.User.Username() != "" {
+			userHost = parsed.User.Username() + "@" + userHost
+		}
+		return endpoint{ssh: true, host: userHost, port: parsed.Port()}, true
+	}
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Hostname() == "" {
+		return endpoint{}, false
+	}
+	port := parsed.Port()
+	switch parsed.Scheme {
+	case "https", "http":
+		if proxy, proxyErr := http.ProxyFromEnvironment(&http.Request{URL: parsed}); proxyErr != nil || proxy != nil {
+			return endpoint{}, false
+		}
+		if port == "" {
+			port = "443"
+			if parsed.Scheme == "http" {
+				port = "80"
+			}
+		}
+	case "git":
+		if port == "" {
+			port = "9418"
+		}
+	default:
+		return endpoint{}, false
+	}
+	return endpoint{host: parsed.Hostname(), port: port}, true
+}
+
+// checkEndpoint returns an error if a connection can't be made to ep. For SSH, the server must also identify itself.
+func checkEndpoint(ctx context.Context, ep endpoint) error {
+	addr := net.JoinHostPort(ep.host, ep.port)
+	if ep.ssh {
+		var ok bool
+		if addr, ok = sshAddress(ctx, ep); !ok {
+			return nil
+		}
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return errs.NewWithCause("unable to reach "+addr, err)
+	}
+	defer xio.CloseIgnoringErrors(conn)
+	if !ep.ssh {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = conn.SetReadDeadline(deadline); err != nil {
+			return errs.Wrap(err)
+		}
+	}
+	var banner string
+	if banner, err = bufio.NewReader(conn).ReadString('\n'); err != nil {
+		return errs.NewWithCause("unable to reach the SSH server at "+addr, err)
+	}
+	if !strings.HasPrefix(banner, "SSH-") {
+		return errs.New("no SSH server at " + addr)
+	}
+	return nil
+}
+
+// sshAddress returns the address ssh would connect to for ep, taking its configuration, where the host may be an
+// alias, into account. Returns false if ssh would connect through a proxy.
+func sshAddress(ctx context.Context, ep endpoint) (string, bool) {
+	_, host, found := strings.Cut(ep.host, "@")
+	if !found {
+		host = ep.host
+	}
+	port := ep.port
+	if port == "" {
+		port = "22"
+	}
+	args := []string{"-G"}
+	if ep.port != "" {
+		args = append(args, "-p", ep.port)
+	}
+	out, err := exec.CommandContext(ctx, "ssh", append(args, ep.host)...).Output()
+	if err != nil {
+		// Without ssh's view of its configuration, assume there is none
+		return net.JoinHostPort(host, port), true
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch key {
+		case "hostname":
+			host = value
+		case "port":
+			port = value
+		case "proxyjump", "proxycommand":
+			if value != "none" {
+				return "", false
+			}
+		default:
+		}
+	}
+	return net.JoinHostPort(host, port), true
+}
+
+// failUnreachable records the repo as having failed, without being processed, because the preflight check couldn't
+// reach its host.
+func (r *repo) failUnreachable() {
+	r.fail("preflight failed", multirepo.Classify(r.unreachable, r.unreachable.Error()))
+}
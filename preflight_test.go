@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/richardwilkes/toolbox/check"
+)
+
+func TestPreflightEndpoint(t *testing.T) {
+	// Hosts reached through a proxy aren't checked
+	for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+		t.Setenv(name, "")
+	}
+	saved := rewriteRemote
+	defer func() { rewriteRemote = saved }()
+	r := &repo{location: &location{url: "git@github.com:org/repo.git", missing: true}}
+
+	rewriteRemote = ""
+	ep, ok := remoteEndpoint(r.reachedURL())
+	check.True(t, ok)
+	check.Equal(t, endpoint{ssh: true, host: "git@github.com"}, ep)
+
+	// With the origin rewritten to HTTPS, as where a network blocks SSH, git reaches the host on port 443 instead
+	rewriteRemote = rewriteHTTPS
+	ep, ok = remoteEndpoint(r.reachedURL())
+	check.True(t, ok)
+	check.Equal(t, endpoint{host: "github.com", port: "443"}, ep)
+
+	r.url = "https://github.com/org/repo.git"
+	rewriteRemote = rewriteSSH
+	ep, ok = remoteEndpoint(r.reachedURL())
+	check.True(t, ok)
+	check.Equal(t, endpoint{ssh: true, host: "git@github.com"}, ep)
+
+	// Local paths can't be rewritten and aren't checked
+	r.url = "/srv/git/repo.git"
+	_, ok = remoteEndpoint(r.reachedURL())
+	check.False(t, ok)
+}
@@ -0,0 +1,540 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/cmdline"
+	"github.com/richardwilkes/toolbox/xio/fs"
+)
+
+var (
+	behindOnly bool
+	fetchOnly  bool
+	autostash  bool
+	rebase     bool
+	ffOnly     bool
+	push       bool
+	submodules bool
+	lfs        bool
+	untracked  bool
+	upstream   bool
+	prune      bool
+	pruneLocal bool
+	branches   []string
+	onDefault  bool
+	showLog    bool
+	diffstat   bool
+)
+
+type pullCmd struct{}
+
+func (c *pullCmd) Name() string {
+	return "pull"
+}
+
+func (c *pullCmd) Usage() string {
+	return "Pulls unmodified git repos. This is the default command."
+}
+
+func (c *pullCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	addCommonOptions(cl)
+	cl.NewGeneralOption(&behindOnly).SetName("behind-only").
+		SetUsage("Fetch first and only pull repos that are behind their upstream")
+	cl.NewGeneralOption(&fetchOnly).SetName("fetch").
+		SetUsage("Fetch from all remotes and report ahead/behind counts rather than pulling")
+	cl.NewGeneralOption(&autostash).SetName("autostash").
+		SetUsage("Rather than skipping repos with unstaged changes to tracked files, stash them before pulling and " +
+			"restore them afterward")
+	cl.NewGeneralOption(&rebase).SetName("rebase").
+		SetUsage("Rebase local commits onto the upstream rather than merging")
+	cl.NewGeneralOption(&ffOnly).SetName("ff-only").
+		SetUsage("Only update branches that can be fast-forwarded")
+	cl.NewGeneralOption(&push).SetName("push").
+		SetUsage("Push the branch of clean repos that are ahead of their upstream after pulling")
+	cl.NewGeneralOption(&submodules).SetName("submodules").
+		SetUsage("Update submodules after a successful pull")
+	cl.NewGeneralOption(&lfs).SetName("lfs").
+		SetUsage("Run \"git lfs pull\" after a successful pull in repos whose .gitattributes use LFS")
+	cl.NewGeneralOption(&untracked).SetName("allow-untracked").
+		SetUsage("Pull repos whose only local changes are untracked files, rather than skipping them")
+	cl.NewGeneralOption(&upstream).SetName("set-upstream").
+		SetUsage("For branches without an upstream, use the remote branch of the same name as the " +
+			"upstream, if there is one")
+	cl.NewGeneralOption(&remoteName).SetName("remote").SetArg("name").
+		SetUsage("Pull the branch of the same name from the remote, rather than the branch's upstream")
+	cl.NewGeneralOption(&allRemotes).SetName("all-remotes").
+		SetUsage("Fetch from every remote when pulling, rather than just the one the branch pulls from")
+	cl.NewGeneralOption(&prune).SetName("prune").
+		SetUsage("Remove remote-tracking branches and tags that no longer exist on the remote")
+	cl.NewGeneralOption(&pruneLocal).SetName("prune-branches").
+		SetUsage("Implies --prune. Also delete local branches whose upstream no longer exists and which have been " +
+			"fully merged into the default branch")
+	addTagsOption(cl)
+	cl.NewGeneralOption(&unshallow).SetName("unshallow").
+		SetUsage("Fetch the complete history of shallow clones before pulling them, rather than " +
+			"just warning about them")
+	cl.NewGeneralOption(&autoAbort).SetName("auto-abort").
+		SetUsage("Should a pull fail part way through, leaving a merge or rebase unfinished, abort it to restore the " +
+			"repo to its prior state")
+	cl.NewGeneralOption(&onDefault).SetName("checkout-default").
+		SetUsage("Before pulling, switch repos without local changes to their default branch, " +
+			"as determined by origin/HEAD")
+	cl.NewGeneralOption(&showLog).SetName("show-log").
+		SetUsage("Once all repos have been processed, list the commits each pull brought in")
+	cl.NewGeneralOption(&diffstat).SetName("diffstat").
+		SetUsage("Once all repos have been processed, show the diffstat of the changes each pull brought in")
+	postPull := defaults.PostPull
+	cl.NewOption(&overrideValue{
+		GeneralValue: cmdline.GeneralValue{Value: &postPull},
+		apply:        func() { overrides.PostPull = postPull },
+	}).SetName("post-pull").SetArg("command").
+		SetUsage("A shell command to run in each repo whose pull brought in changes, such as \"go mod download\". " +
+			"The variables {path}, {name}, {branch}, and {remote_url} are replaced as they are by the exec command, " +
+			"each quoted as a single word for the shell, so they must not be quoted again. Takes precedence over the " +
+			"post_pull and post_pull_repos configuration settings")
+	cl.NewGeneralOption(&bare).SetName("bare").
+		SetUsage("Also find bare repos, such as mirrors, and update them with \"git remote update --prune\", " +
+			"reporting the refs that changed, rather than pulling")
+	cl.NewGeneralOption(&useJJ).SetName("jj").
+		SetUsage("Update the repos colocated with a Jujutsu repo using jj, running \"jj git fetch\" and then " +
+			"rebasing the working copy's changes onto the trunk, rather than skipping them")
+	cl.NewGeneralOption(&forceGit).SetName("force-git").
+		SetUsage("Pull the repos colocated with a Jujutsu repo using git, as any other, rather than skipping them. " +
+			"This may leave jj's view of them out of sync until it next imports the changes")
+	cl.NewGeneralOption(&branches).SetName("branch").SetArg("glob").
+		SetUsage("Only pull repos whose current branch matches the glob. May be specified more than once")
+	addCloneMissingOption(cl)
+	addDryRunOption(cl)
+	paths := cl.Parse(args)
+	if err := multirepo.ValidatePatterns(branches); err != nil {
+		cl.FatalMsg(multirepo.ErrorText(err))
+	}
+	if pruneLocal {
+		prune = true
+	}
+	if remoteName != "" && allRemotes {
+		cl.FatalMsg("--remote and --all-remotes may not be used together")
+	}
+	if useJJ && forceGit {
+		cl.FatalMsg("--jj and --force-git may not be used together")
+	}
+	if rebase && ffOnly {
+		cl.FatalMsg("--rebase and --ff-only may not be used together")
+	}
+	action := pullRepo
+	if fetchOnly {
+		action = fetchRepo
+	}
+	run(paths, func(r *repo) {
+		switch {
+		case r.bare:
+			updateMirror(r)
+		case forceGit || !r.isJJRepo():
+			action(r)
+		case useJJ:
+			jjPull(r)
+		default:
+			r.skipState("skipped: jj repo")
+		}
+	})
+	return nil
+}
+
+func pullRepo(r *repo) {
+	if !r.showBranch() {
+		return
+	}
+	if op := r.operationInProgress(); op != "" {
+		r.skipState("skipped: " + op + " in progress")
+		return
+	}
+	if onDefault {
+		note, ok := r.checkoutDefault()
+		if !ok {
+			return
+		}
+		if note != "" {
+			defer func() {
+				if r.result.Outcome != multirepo.Failed {
+					r.annotate(note)
+				}
+			}()
+		}
+	}
+	if r.result.Branch == "" {
+		r.skipState("detached HEAD")
+		return
+	}
+	if len(branches) != 0 && !slices.ContainsFunc(branches, func(pattern string) bool {
+		matched, _ := path.Match(pattern, r.result.Branch)
+		return matched
+	}) {
+		r.skipState("skipped: on " + r.result.Branch)
+		return
+	}
+	if !r.hasUpstream() {
+		if remoteName != "" {
+			r.skipState("no remote named " + remoteName)
+			return
+		}
+		if !upstream || dryRun {
+			r.skipState("no upstream")
+			return
+		}
+		ref := r.setUpstream()
+		if ref == "" {
+			r.skipState("no upstream")
+			return
+		}
+		defer func() {
+			if r.result.Outcome != multirepo.Failed {
+				r.annotate("upstream set to " + ref)
+			}
+		}()
+	}
+	local, err := r.localChanges()
+	if err != nil {
+		r.fail("skipped due to error", err)
+		return
+	}
+	pending := local.total()
+	if untracked && local.untracked != 0 {
+		pending -= local.untracked
+		defer func() {
+			if r.result.Outcome != multirepo.Failed && r.result.Outcome != multirepo.Skipped {
+				r.annotate(fmt.Sprintf("%d untracked %s", local.untracked, plural(local.untracked, "file", "files")))
+			}
+		}()
+	}
+	stash := false
+	if pending != 0 {
+		if !autostash || local.staged != 0 || local.unstaged == 0 {
+			r.skip("changes")
+			return
+		}
+		stash = true
+	}
+	shallow := r.isShallow()
+	if shallow && (!unshallow || dryRun) {
+		defer func() {
+			if r.result.Outcome != multirepo.Failed {
+				r.warn("shallow clone")
+			}
+		}()
+	}
+	if dryRun {
+		r.reportPull(stash)
+		return
+	}
+	if shallow && unshallow {
+		if _, err = r.gitWithProgress("fetch", "--unshallow"); err != nil {
+			r.fail("failed to unshallow", err)
+			return
+		}
+		defer func() {
+			if r.result.Outcome != multirepo.Failed {
+				r.annotate("unshallowed")
+			}
+		}()
+	}
+	if behindOnly {
+		args := []string{"fetch"}
+		if prune {
+			args = append(args, "--prune", "--prune-tags")
+		}
+		args = append(args, tagArgs()...)
+		switch {
+		case remoteName != "":
+			args = append(args, remoteName)
+		case allRemotes:
+			args = append(args, "--all")
+		default:
+		}
+		if _, err = r.gitWithProgress(args...); err != nil {
+			r.fail("failed to fetch", err)
+			return
+		}
+		if !r.trackUpstream() {
+			r.skipState("no upstream")
+			return
+		}
+		if r.result.Behind == 0 {
+			r.succeeded("up to date")
+			r.warnUnpushed()
+			return
+		}
+	}
+	if stash {
+		// Compare the stash ref before and after, so that we never pop a stash we didn't create
+		before, _ := r.gitActual("rev-parse", "-q", "--verify", "refs/stash")
+		if _, err = r.git("stash", "push", "-m", "gp autostash"); err != nil {
+			r.fail("failed to stash", err)
+			return
+		}
+		after, _ := r.gitActual("rev-parse", "-q", "--verify", "refs/stash")
+		stash = after != "" && after != before
+	}
+	r.pull()
+	if submodules && r.result.Outcome != multirepo.Failed {
+		r.updateSubmodules()
+	}
+	if lfs && r.result.Outcome != multirepo.Failed {
+		r.pullLFS()
+	}
+	if pruneLocal && r.result.Outcome != multirepo.Failed {
+		r.pruneBranches()
+	}
+	if stash {
+		r.popStash()
+	}
+	if r.result.Outcome == multirepo.Updated {
+		r.runPostPull()
+	}
+	if r.result.Outcome != multirepo.Failed {
+		if push && pending == 0 && r.result.Ahead > 0 && r.result.Behind == 0 {
+			r.push()
+		}
+		r.warnUnpushed()
+	}
+}
+
+func (r *repo) push() {
+	count := r.result.Ahead
+	if _, err := r.git("push"); err != nil {
+		r.fail(r.result.Status+"; failed to push", err)
+		return
+	}
+	r.trackUpstream()
+	r.annotate(fmt.Sprintf("pushed %d %s", count, plural(count, "commit", "commits")))
+}
+
+// updateSubmodules initializes and updates the repo's submodules, if it has any.
+func (r *repo) updateSubmodules() {
+	if !fs.FileExists(filepath.Join(r.path, ".gitmodules")) {
+		return
+	}
+	out, err := r.git("submodule", "update", "--init", "--recursive")
+	if err != nil {
+		r.fail(r.result.Status+"; submodule update failed", err)
+		return
+	}
+	if out != "" {
+		r.annotate("submodules updated")
+	}
+}
+
+// pullLFS fetches and checks out Git LFS objects, if the repo uses LFS.
+func (r *repo) pullLFS() {
+	data, err := os.ReadFile(filepath.Join(r.path, ".gitattributes"))
+	if err != nil || !bytes.Contains(data, []byte("filter=lfs")) {
+		return
+	}
+	var out string
+	if out, err = r.git("lfs", "pull"); err != nil {
+		r.fail(r.result.Status+"; lfs pull failed", err)
+		return
+	}
+	if out != "" {
+		lines := strings.Split(out, "\n")
+		r.annotate("lfs: " + strings.TrimSpace(lines[len(lines)-1]))
+	}
+}
+
+// checkoutDefault switches the repo to its default branch, if it isn't already on it. Returns a note describing the
+// switch, if one was made, and false if the repo was finished with instead.
+func (r *repo) checkoutDefault() (string, bool) {
+	def, err := r.defaultBranch()
+	if err != nil {
+		r.skipState("no default branch")
+		return "", false
+	}
+	_, branch, _ := strings.Cut(def, "/")
+	if r.result.Branch == branch {
+		return "", true
+	}
+	var local changes
+	if local, err = r.localChanges(); err != nil {
+		r.fail("skipped due to error", err)
+		return "", false
+	}
+	from := r.result.Branch
+	if from == "" {
+		from = "detached HEAD"
+	}
+	if local.total() != 0 {
+		r.skip("changes on " + from)
+		return "", false
+	}
+	if dryRun {
+		r.notice("would switch from " + from + " to " + branch + " and pull")
+		return "", false
+	}
+	var found bool
+	if found, err = r.switchTo(branch); err != nil {
+		r.fail("failed to switch to "+branch, err)
+		return "", false
+	}
+	if !found {
+		r.skipState("no branch " + branch)
+		return "", false
+	}
+	return "switched from " + from, true
+}
+
+// pruneBranches deletes the local branches whose upstream is gone and which have been merged into the default branch.
+func (r *repo) pruneBranches() {
+	def, err := r.defaultBranch()
+	if err != nil {
+		r.warn("unable to prune branches: " + multirepo.ErrorText(err))
+		return
+	}
+	var list []string
+	if list, err = r.localBranches(def, true); err != nil {
+		r.warn("unable to prune branches: " + multirepo.ErrorText(err))
+		return
+	}
+	var count int
+	if count, err = r.deleteBranches(list); err != nil {
+		r.warn("unable to prune branches: " + multirepo.ErrorText(err))
+		return
+	}
+	if count != 0 {
+		r.annotate(fmt.Sprintf("pruned %d %s", count, plural(count, "branch", "branches")))
+	}
+}
+
+// reportPull describes what pulling would do, without doing it. The upstream is as of the last fetch, since fetching
+// would alter the repo.
+func (r *repo) reportPull(stash bool) {
+	if !r.trackUpstream() {
+		r.skipState("no upstream")
+		return
+	}
+	if behindOnly && r.result.Behind == 0 {
+		r.succeeded("up to date as of the last fetch")
+		return
+	}
+	msg := "would pull"
+	if stash {
+		msg += " with autostash"
+	}
+	r.notice(msg)
+}
+
+// warnUnpushed adds a warning if the branch has commits that haven't been pushed to its upstream.
+func (r *repo) warnUnpushed() {
+	if r.result.Outcome != multirepo.Failed && r.result.Ahead > 0 {
+		r.warn(fmt.Sprintf("%d unpushed %s", r.result.Ahead, plural(r.result.Ahead, "commit", "commits")))
+	}
+}
+
+func (r *repo) pull() {
+	args := []string{"pull"}
+	if prune {
+		// pull doesn't accept --prune-tags, so set the equivalent configuration
+		args = []string{"-c", "fetch.pruneTags=true", "pull", "--prune"}
+	}
+	args = append(args, tagArgs()...)
+	prefix := "failed to pull"
+	mode := r.pullMode()
+	switch mode {
+	case pullRebase:
+		args = append(args, "--rebase")
+		prefix += " with rebase"
+	case pullFFOnly:
+		args = append(args, "--ff-only")
+		prefix += " with fast-forward only"
+	default:
+	}
+	switch {
+	case remoteName != "":
+		args = append(args, remoteName, r.result.Branch)
+	case allRemotes:
+		args = append(args, "--all")
+	default:
+	}
+	var before string
+	if showLog || diffstat {
+		before, _ = r.gitActual("rev-parse", "HEAD")
+	}
+	out, err := r.gitWithProgress(args...)
+	if err != nil {
+		r.fail(prefix, err)
+		if autoAbort {
+			r.abortFailedPull()
+		}
+		return
+	}
+	if before != "" {
+		r.recordIncoming(before)
+	}
+	r.trackUpstream()
+	for _, s := range strings.Split(out, "\n") {
+		if strings.Contains(s, " changed, ") {
+			r.changed(strings.TrimSpace(s))
+			return
+		}
+	}
+	if mode == pullRebase && strings.Contains(out, "Successfully rebased") {
+		r.changed("rebased onto upstream")
+		return
+	}
+	r.succeeded("no changes")
+}
+
+// pullMode returns the pull strategy to use. The command line options take precedence over any configuration.
+func (r *repo) pullMode() string {
+	switch {
+	case rebase:
+		return pullRebase
+	case ffOnly:
+		return pullFFOnly
+	default:
+		return r.cfg.Pull
+	}
+}
+
+// recordIncoming records the commits, the diffstat, or both, between before and the current HEAD, as requested.
+func (r *repo) recordIncoming(before string) {
+	after, err := r.gitActual("rev-parse", "HEAD")
+	if err != nil || after == before {
+		return
+	}
+	var out string
+	if showLog {
+		if out, err = r.git("log", "--oneline", before+".."+after); err == nil && out != "" {
+			r.result.Commits = strings.Split(out, "\n")
+		}
+	}
+	if diffstat {
+		if out, err = r.git("diff", "--stat", before+".."+after); err == nil && out != "" {
+			// Each line is indented by a space, although the output has been trimmed of the first one
+			for _, line := range strings.Split(out, "\n") {
+				r.result.Diffstat = append(r.result.Diffstat, strings.TrimPrefix(line, " "))
+			}
+		}
+	}
+}
+
+// popStash restores the changes stashed prior to pulling, noting in the result if that couldn't be done cleanly.
+func (r *repo) popStash() {
+	if _, err := r.gitActual("stash", "pop"); err != nil {
+		prefix := "stash pop conflicted"
+		if r.result.Outcome == multirepo.Failed {
+			prefix = r.result.Status + "; " + prefix
+		}
+		r.fail(prefix, err)
+		return
+	}
+	if r.result.Outcome != multirepo.Failed {
+		r.annotate("autostashed")
+	}
+}
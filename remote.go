@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+var (
+	remoteMatch string
+	remoteName  string
+	allRemotes  bool
+)
+
+// normalizeRemoteURL reduces the various forms a git remote URL can take to "host/path", so that, for example,
+// "git@github.com:org/repo.git" and "https://github.com/org/repo" both become "github.com/org/repo".
+func normalizeRemoteURL(url string) string {
+	url = strings.TrimSpace(url)
+	if hasDrivePrefix(url) {
+		return strings.TrimSuffix(strings.TrimSuffix(filepath.ToSlash(url), "/"), ".git")
+	}
+	if i := strings.Index(url, "://"); i != -1 {
+		url = url[i+3:]
+	} else if i = strings.Index(url, ":"); i != -1 && !strings.Contains(url[:i], "/") {
+		// scp-like syntax, e.g. user@host:path
+		url = url[:i] + "/" + url[i+1:]
+	}
+	if i := strings.Index(url, "@"); i != -1 && i < strings.Index(url+"/", "/") {
+		url = url[i+1:]
+	}
+	if host, rest, found := strings.Cut(url, "/"); found {
+		// Drop any port, e.g. host:22/path
+		if i := strings.Index(host, ":"); i != -1 {
+			host = host[:i]
+		}
+		url = host + "/" + rest
+	}
+	url = strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	return url
+}
+
+// hasDrivePrefix returns true if url is a local path starting with a Windows drive letter, such as C:\repos\gp, which
+// git would otherwise take for the scp-like syntax. As with git, this is only considered on Windows.
+func hasDrivePrefix(url string) bool {
+	return runtime.GOOS == "windows" && len(url) >= 2 && url[1] == ':' &&
+		(('a' <= url[0] && url[0] <= 'z') || ('A' <= url[0] && url[0] <= 'Z'))
+}
+
+// originURL returns the URL of the origin remote for the repo at dir, or an empty string if there isn't one.
+func originURL(dir string) string {
+	if useGoGit {
+		return goGitOriginURL(dir)
+	}
+	out, err := exec.Command("git", "-C", dir, "config", "--get", "remote.origin.url").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// filterByRemote returns the repos in list whose origin URL matches the glob pattern, which is compared against the
+// normalized form of the URL. Repos that haven't been checked out yet are matched against the URL they would be cloned
+// from.
+func filterByRemote(list []string, locs map[string]*location, pattern string) []string {
+	keep := make([]bool, len(list))
+	var wg sync.WaitGroup
+	limiter := make(chan struct{}, jobs)
+	for i, p := range list {
+		wg.Add(1)
+		limiter <- struct{}{}
+		go func(i int, p string) {
+			defer func() {
+				<-limiter
+				wg.Done()
+			}()
+			url := locs[p].url
+			if !locs[p].missing {
+				url = originURL(p)
+			}
+			keep[i] = remoteMatches(pattern, url)
+		}(i, p)
+	}
+	wg.Wait()
+	filtered := make([]string, 0, len(list))
+	for i, p := range list {
+		if keep[i] {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// remoteMatches returns true if the glob pattern matches the url. A trailing "*" in the pattern is permitted to match
+// across path separators, so that "github.com/org/*" also matches repos in nested groups.
+func remoteMatches(pattern, url string) bool {
+	if url == "" {
+		return false
+	}
+	url = normalizeRemoteURL(url)
+	pattern = normalizeRemoteURL(pattern)
+	if ok, _ := path.Match(pattern, url); ok {
+		return true
+	}
+	if prefix, found := strings.CutSuffix(pattern, "/*"); found {
+		return strings.HasPrefix(url, prefix+"/")
+	}
+	return false
+}
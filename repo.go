@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/errs"
+	"github.com/richardwilkes/toolbox/xio/term"
+)
+
+type repo struct {
+	*location
+	ctx         context.Context
+	path        string
+	printer     chan *msgInfo
+	row         int
+	col         int
+	result      multirepo.Result
+	statusColor term.Color
+	statusStyle term.Style
+	branchCol   int
+	tracking    string
+	transcript  []string
+	name        string          // the repo's path relative to the root, as used in the summary
+	label       string          // the repo's name, as displayed at the start of its row
+	finished    bool            // true once an outcome has been recorded
+	heading     int             // the row of the header for the repo's group, if grouped
+	committed   time.Time       // when the most recent commit was made, if sorting by mtime
+	synced      time.Time       // when processing finished, in watch mode
+	goGitRepo   *git.Repository // opened upon first use, with --go-git
+	progress    string          // the progress last shown, if any
+	rewrite     []string        // the environment that rewrites the origin URL, with --rewrite-remote
+	notable     bool            // true if the outcome deserves attention, though the repo was left unchanged
+	unreachable error           // why the host of the repo's origin couldn't be reached, with --preflight
+	releaseSSH  func()          // lets the other repos on the same SSH host proceed, with --ssh-multiplex
+}
+
+// processRepos applies action to each of the repos received from work. If finished isn't nil, each repo is sent to it
+// once it has been processed.
+func processRepos(wg *sync.WaitGroup, work <-chan *repo, finished chan<- *repo, action func(r *repo)) {
+	defer wg.Done()
+	for r := range work {
+		switch {
+		case r.ctx.Err() != nil:
+			r.abort()
+		case isIgnored(r.path):
+			r.ignore()
+		case r.missing && !cloneMissing:
+			r.skip("missing checkout")
+		case r.unreachable != nil:
+			r.failUnreachable()
+		case !r.missing && r.isDormant():
+			r.skip("no activity in " + formatAge(skipStale))
+		default:
+			start := time.Now()
+			r.printer <- &msgInfo{row: r.row, started: true}
+			r.rewrite = r.rewriteEnv()
+			release := r.awaitSSHConnection()
+			if r.missing {
+				cloneRepo(r)
+			} else {
+				action(r)
+			}
+			release()
+			if watch > 0 {
+				r.synced = time.Now()
+			}
+			r.markDuration(start)
+			if r.ctx.Err() != nil && r.result.Outcome == multirepo.Failed {
+				r.abort()
+			}
+			if sortBy == sortMTime {
+				r.recordCommitTime()
+			}
+			if recordingHistory() {
+				r.recordHead()
+			}
+		}
+		r.printer <- &msgInfo{row: r.row, done: true, outcome: r.result.Outcome, heading: r.heading, quiet: r.quiet()}
+		if finished != nil {
+			finished <- r
+		}
+	}
+}
+
+// showBranch displays the repo's current branch. Returns false if the branch could not be determined, in which case the
+// error has already been displayed.
+func (r *repo) showBranch() bool {
+	branch, err := r.git("branch", "--show-current")
+	if err != nil {
+		r.fail("skipped due to error", err)
+		return false
+	}
+	r.result.Branch = branch
+	r.branchCol = r.col
+	r.drawBranch()
+	return true
+}
+
+// drawBranch (re)displays the branch, along with any upstream tracking information, leaving the current position just
+// past it. Since this erases anything to its right, it must be called before the status is displayed.
+func (r *repo) drawBranch() {
+	r.col = r.branchCol
+	r.show("[", textColor, term.Normal)
+	r.col++
+	r.show(r.result.Branch, textColor, term.Bold)
+	r.col += utf8.RuneCountInString(r.result.Branch)
+	if remoteName != "" {
+		r.show(" ← "+remoteName, textColor, term.Normal)
+		r.col += utf8.RuneCountInString(remoteName) + 3
+	}
+	if r.tracking != "" {
+		r.show(r.tracking, noticeColor, term.Bold)
+		r.col += utf8.RuneCountInString(r.tracking)
+	}
+	r.show("]", textColor, term.Normal)
+	r.col += 2
+}
+
+// trackUpstream records how far the current branch is ahead of and behind its upstream and adds that to the branch
+// display. Returns false if there is no upstream.
+func (r *repo) trackUpstream() bool {
+	ahead, behind, err := r.aheadBehind()
+	if err != nil {
+		return false
+	}
+	r.result.Ahead = ahead
+	r.result.Behind = behind
+	r.tracking = ""
+	if ahead > 0 {
+		r.tracking += fmt.Sprintf(" ↑%d", ahead)
+	}
+	if behind > 0 {
+		r.tracking += fmt.Sprintf(" ↓%d", behind)
+	}
+	r.drawBranch()
+	if r.result.Status != "" {
+		r.drawStatus()
+	}
+	return true
+}
+
+// show sends a message to the printer for display at the repo's current position.
+func (r *repo) show(msg string, color term.Color, style term.Style) {
+	r.printer <- &msgInfo{
+		msg:   msg,
+		row:   r.row,
+		col:   r.col,
+		color: color,
+		style: style,
+	}
+}
+
+// changes holds counts of the different kinds of local changes in a repo.
+type changes struct {
+	staged    int
+	unstaged  int
+	untracked int
+}
+
+func (c changes) total() int {
+	return c.staged + c.unstaged + c.untracked
+}
+
+// localChanges returns counts of the local changes in the repo, as reported by "git status --porcelain=v2".
+func (r *repo) localChanges() (c changes, err error) {
+	var out string
+	if out, err = r.git("status", "--porcelain=v2"); err != nil {
+		return c, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) < 4 {
+			continue
+		}
+		switch line[0] {
+		case '?':
+			c.untracked++
+		case 'u':
+			c.staged++
+		case '1', '2':
+			if line[2] != '.' {
+				c.staged++
+			}
+			if line[3] != '.' {
+				c.unstaged++
+			}
+		}
+	}
+	return c, nil
+}
+
+// hasUpstream returns true if the current branch has an upstream configured or, if a remote was selected with
+// --remote, that the remote exists.
+func (r *repo) hasUpstream() bool {
+	if remoteName != "" {
+		_, err := r.gitActual("remote", "get-url", remoteName)
+		return err == nil
+	}
+	_, err := r.gitActual("rev-parse", "--abbrev-ref", "@{upstream}")
+	return err == nil
+}
+
+// upstreamRef returns the ref the current branch is compared against: the branch of the same name on the remote
+// selected with --remote, or else the branch's upstream.
+func (r *repo) upstreamRef() string {
+	if remoteName != "" {
+		return "refs/remotes/" + remoteName + "/" + r.result.Branch
+	}
+	return "@{upstream}"
+}
+
+// setUpstream sets the upstream of the current branch to the remote branch of the same name, preferring origin should
+// more than one remote have one. Returns the upstream that was set, or an empty string if there was no suitable remote
+// branch or setting it failed.
+func (r *repo) setUpstream() string {
+	out, err := r.gitActual("remote")
+	if err != nil || out == "" {
+		return ""
+	}
+	var candidates []string
+	for _, remote := range strings.Split(out, "\n") {
+		ref := remote + "/" + r.result.Branch
+		if _, err = r.gitActual("rev-parse", "-q", "--verify", "refs/remotes/"+ref); err == nil {
+			if remote == "origin" {
+				candidates = append([]string{ref}, candidates...)
+			} else {
+				candidates = append(candidates, ref)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	if _, err = r.gitActual("branch", "--set-upstream-to="+candidates[0]); err != nil {
+		return ""
+	}
+	return candidates[0]
+}
+
+// aheadBehind returns the number of commits the current branch is ahead and behind its upstream. An error is returned
+// if there is no upstream.
+func (r *repo) aheadBehind() (ahead, behind int, err error) {
+	ref := r.upstreamRef()
+	if _, err = r.gitActual("rev-parse", "-q", "--verify", ref); err != nil {
+		return 0, 0, errs.New("no upstream")
+	}
+	var out string
+	if out, err = r.git("rev-list", "--left-right", "--count", "HEAD..."+ref); err != nil {
+		return 0, 0, err
+	}
+	if _, err = fmt.Sscanf(out, "%d %d", &ahead, &behind); err != nil {
+		return 0, 0, errs.Wrap(err)
+	}
+	return ahead, behind, nil
+}
+
+// runner returns the runner for commands within the repo's directory or, if the repo hasn't been checked out yet, its
+// parent directory.
+func (r *repo) runner() *multirepo.Runner {
+	dir := r.path
+	if r.missing {
+		dir = filepath.Dir(r.path)
+	}
+	env := prompts.env
+	if prompts.served != nil {
+		env = append(slices.Clip(env), askpassRepoEnv+"="+r.path)
+	}
+	if len(r.rewrite) != 0 {
+		env = append(slices.Clip(env), r.rewrite...)
+	}
+	runner := &multirepo.Runner{
+		Dir:        dir,
+		Env:        env,
+		Timeout:    *r.cfg.Timeout,
+		Retries:    *r.cfg.Retries,
+		RetryDelay: *r.cfg.RetryDelay,
+		OnCommand: func(c *exec.Cmd, start time.Time, out string, err error) {
+			logCommand(c, start, out, err)
+			if r.releaseSSH != nil && networkCommand(c.Args) {
+				r.releaseSSH()
+			}
+			r.forgetRefusedAnswers(out, err)
+			if tui || verbose {
+				r.record(c.String(), out, err)
+			}
+		},
+		OnRetry: func(attempt, attempts int, remaining time.Duration, err error) {
+			msg := fmt.Sprintf("retrying in %ds… attempt %d/%d", int(math.Ceil(remaining.Seconds())), attempt, attempts)
+			var ce *multirepo.ClassifiedError
+			if errors.As(err, &ce) {
+				msg += " (" + ce.Kind + ")"
+			}
+			r.show(msg, noticeColor, term.Bold)
+		},
+	}
+	if useGoGit {
+		runner.Intercept = r.runGoGit
+	}
+	return runner
+}
+
+// git runs git with args, retrying failures that look transient.
+func (r *repo) git(args ...string) (string, error) {
+	return r.runner().Git(r.ctx, args...)
+}
+
+// gitWithProgress runs git with args, as git does. When the display is updated in place, git is asked to report its
+// progress, which is shown in place of the status until the command completes.
+func (r *repo) gitWithProgress(args ...string) (string, error) {
+	if !liveProgress {
+		return r.git(args...)
+	}
+	// The option belongs to the subcommand, which follows any configuration given with -c
+	i := 0
+	for i+1 < len(args) && args[i] == "-c" {
+		i += 2
+	}
+	runner := r.runner()
+	runner.OnProgress = r.showProgress
+	return runner.Git(r.ctx, slices.Insert(slices.Clone(args), i+1, "--progress")...)
+}
+
+// showProgress displays the phase and percentage of a command's progress, unless that is what is already shown.
+func (r *repo) showProgress(phase string, percent int) {
+	if msg := fmt.Sprintf("%s %d%%", strings.ToLower(phase), percent); msg != r.progress {
+		r.progress = msg
+		r.show(msg, textColor, term.Normal)
+	}
+}
+
+// gitActual runs git with args once. With --go-git, the operation is performed in-process instead, where possible.
+func (r *repo) gitActual(args ...string) (string, error) {
+	return r.runner().GitOnce(r.ctx, args...)
+}
+
+// run executes the named program with args within the repo's directory, returning its combined output, even when it
+// fails.
+func (r *repo) run(name string, args ...string) (string, error) {
+	return r.runner().Run(r.ctx, name, args...)
+}
+
+// record retains the output of a command, as well as any error it produced, for later display.
+func (r *repo) record(cmd, out string, err error) {
+	msg := "$ " + cmd
+	if out != "" {
+		msg += "\n" + out
+	}
+	if err != nil {
+		msg += "\n" + multirepo.ErrorText(err)
+	}
+	r.transcript = append(r.transcript, msg)
+	if tui {
+		r.printer <- &msgInfo{row: r.row, msg: msg, transcript: true}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+var reportPath string
+
+// runReport is the report of a run written with --report in JSON.
+type runReport struct {
+	Started  time.Time           `json:"started"`
+	Finished time.Time           `json:"finished"`
+	Duration float64             `json:"duration"` // in seconds
+	Counts   map[string]int      `json:"counts"`
+	Failed   bool                `json:"failed"`
+	Results  []*multirepo.Result `json:"results"`
+}
+
+// junitSuite is the report of a run written with --report in the JUnit XML format, with each repo as a test case.
+type junitSuite struct {
+	XMLName   xml.Name    `xml:"testsuite"`
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Errors    int         `xml:"errors,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitProblem `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// writeReport writes the report of the run to the file given with --report, replacing any report from a previous run.
+// Files ending in .xml receive JUnit XML, and all others JSON.
+func writeReport(repos []*repo, root string, start time.Time) error {
+	var data []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(reportPath), ".xml") {
+		data, err = junitReport(repos, root, start)
+	} else {
+		data, err = jsonReport(repos, start)
+	}
+	if err != nil {
+		return errs.NewWithCause("unable to create report", err)
+	}
+	tmp := reportPath + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return errs.NewWithCause("unable to write report "+reportPath, err)
+	}
+	if err = os.Rename(tmp, reportPath); err != nil {
+		return errs.NewWithCause("unable to write report "+reportPath, err)
+	}
+	return nil
+}
+
+func jsonReport(repos []*repo, start time.Time) ([]byte, error) {
+	now := time.Now()
+	report := runReport{
+		Started:  start,
+		Finished: now,
+		Duration: now.Sub(start).Seconds(),
+		Counts:   make(map[string]int),
+		Failed:   exitStatus(repos) == exitFailed,
+		Results:  make([]*multirepo.Result, len(repos)),
+	}
+	for i, r := range repos {
+		report.Counts[r.result.Outcome.String()]++
+		report.Results[i] = &r.result
+	}
+	data, err := json.MarshalIndent(&report, "", "  ")
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return append(data, '\n'), nil
+}
+
+func junitReport(repos []*repo, root string, start time.Time) ([]byte, error) {
+	suite := junitSuite{
+		Name:      "gp",
+		Tests:     len(repos),
+		Time:      seconds(time.Since(start).Seconds()),
+		Timestamp: start.Format("2006-01-02T15:04:05"),
+		Cases:     make([]junitCase, len(repos)),
+	}
+	for i, r := range repos {
+		c := junitCase{
+			Name:      displayName(root, r.path),
+			ClassName: "gp",
+			Time:      seconds(r.result.Duration),
+		}
+		switch r.result.Outcome {
+		case multirepo.Failed, multirepo.TimedOut:
+			suite.Failures++
+			c.Failure = &junitProblem{Message: r.result.Status, Type: r.result.Reason, Text: r.result.Error}
+		case multirepo.Aborted:
+			suite.Errors++
+			c.Error = &junitProblem{Message: r.result.Status}
+		case multirepo.Skipped, multirepo.Ignored:
+			suite.Skipped++
+			c.Skipped = &junitProblem{Message: r.result.Status}
+		default:
+			lines := append([]string{r.result.Status}, r.result.Commits...)
+			lines = append(lines, r.result.Warnings...)
+			c.SystemOut = strings.Join(lines, "\n")
+		}
+		suite.Cases[i] = c
+	}
+	data, err := xml.MarshalIndent(&suite, "", "  ")
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return append(append([]byte(xml.Header), data...), '\n'), nil
+}
+
+// seconds formats a duration in seconds as JUnit expects.
+func seconds(s float64) string {
+	return fmt.Sprintf("%.3f", s)
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyResize arranges for c to receive a signal whenever the terminal is resized.
+func notifyResize(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGWINCH)
+}
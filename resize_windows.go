@@ -0,0 +1,7 @@
+package main
+
+import "os"
+
+// notifyResize does nothing, as Windows has no equivalent of SIGWINCH.
+func notifyResize(_ chan<- os.Signal) {
+}
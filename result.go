@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/xio/term"
+)
+
+// finish records the outcome for the repo and displays its status.
+func (r *repo) finish(o multirepo.Outcome, status string, color term.Color, style term.Style) {
+	r.result.Outcome = o
+	r.result.Status = status
+	r.statusColor = color
+	r.statusStyle = style
+	r.finished = true
+	if icons {
+		r.drawLabel()
+		if r.branchCol != 0 {
+			r.drawBranch()
+		}
+	}
+	r.drawStatus()
+}
+
+// annotate appends note to the repo's status and redisplays it.
+func (r *repo) annotate(note string) {
+	r.result.Status += " (" + note + ")"
+	r.drawStatus()
+}
+
+// warn adds a warning to be displayed after the repo's status.
+func (r *repo) warn(msg string) {
+	r.notable = true
+	r.result.Warnings = append(r.result.Warnings, msg)
+	r.drawStatus()
+}
+
+// drawStatus displays the repo's status, followed by the time it took and, in watch mode, when it finished, once known,
+// and any warnings.
+func (r *repo) drawStatus() {
+	status := r.result.Status
+	switch {
+	case icons:
+		status = compactStatus(status)
+	case !r.synced.IsZero():
+		status += fmt.Sprintf(" (%.1fs, synced at %s)", r.result.Duration, r.synced.Format(time.TimeOnly))
+	case r.result.Duration > 0:
+		status += fmt.Sprintf(" (%.1fs)", r.result.Duration)
+	default:
+	}
+	r.show(status, r.statusColor, r.statusStyle)
+	if len(r.result.Warnings) != 0 {
+		col := r.col
+		r.col += utf8.RuneCountInString(status) + 1
+		r.show("— "+strings.Join(r.result.Warnings, ", "), noticeColor, term.Bold)
+		r.col = col
+	}
+}
+
+// succeeded records a successful outcome for the repo and displays msg.
+func (r *repo) succeeded(msg string) {
+	r.finish(multirepo.Unchanged, msg, unchangedColor, term.Normal)
+}
+
+// changed records that the repo was updated, with msg describing the changes.
+func (r *repo) changed(msg string) {
+	r.result.Changes = msg
+	r.finish(multirepo.Updated, msg, updatedColor, term.Bold)
+}
+
+// notice records a successful outcome that nonetheless deserves attention and displays msg.
+func (r *repo) notice(msg string) {
+	r.notable = true
+	r.finish(multirepo.Unchanged, msg, noticeColor, term.Bold)
+}
+
+// quiet returns true if the repo is to be left out of the display with --only-changed, having been left unchanged
+// without anything deserving attention.
+func (r *repo) quiet() bool {
+	return onlyChanged && r.result.Outcome == multirepo.Unchanged && !r.notable
+}
+
+// skip records that the repo was skipped for the given reason.
+func (r *repo) skip(reason string) {
+	r.finish(multirepo.Skipped, skippedPrefix+reason, skippedColor, term.Bold)
+}
+
+// skipState records that the repo was skipped because of the state of its checkout, such as a detached HEAD. These
+// are displayed in their own color, as they call for a different kind of attention than local changes do.
+func (r *repo) skipState(reason string) {
+	r.finish(multirepo.Skipped, reason, stateColor, term.Bold)
+}
+
+// ignore records that the repo was left alone because it is on the list of ignored repos.
+func (r *repo) ignore() {
+	r.finish(multirepo.Ignored, "ignored", ignoredColor, dim)
+}
+
+// fail records that the repo failed with err, displaying it after prefix. Where the kind of failure is known, that is
+// displayed instead of the full error.
+func (r *repo) fail(prefix string, err error) {
+	r.result.Error = multirepo.ErrorText(err)
+	detail := r.result.Error
+	var ce *multirepo.ClassifiedError
+	if errors.As(err, &ce) {
+		r.result.Reason = ce.Kind
+		detail = ce.Kind
+	}
+	r.finish(multirepo.Failed, prefix+": "+detail, failedColor, term.Bold)
+}
+
+// abort records that processing of the repo was cancelled, either by an interrupt or by running out of time.
+func (r *repo) abort() {
+	if errors.Is(r.ctx.Err(), context.DeadlineExceeded) {
+		r.finish(multirepo.TimedOut, "timed out", failedColor, term.Bold)
+		return
+	}
+	r.finish(multirepo.Aborted, "aborted", failedColor, term.Bold)
+}
+
+// markDuration records the time taken since start and redisplays the status to include it.
+func (r *repo) markDuration(start time.Time) {
+	r.result.Duration = time.Since(start).Seconds()
+	if r.result.Status != "" {
+		r.drawStatus()
+	}
+}
+
+func emitJSON(repos []*repo) {
+	results := make([]*multirepo.Result, len(repos))
+	for i, r := range repos {
+		results[i] = &r.result
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(results); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+const (
+	rewriteSSH   = "ssh"
+	rewriteHTTPS = "https"
+)
+
+// rewriteRemote is the form, if any, to which the repos' origin URLs are rewritten for the duration of the run.
+var rewriteRemote string
+
+func validateRewriteRemote() error {
+	switch rewriteRemote {
+	case "", rewriteSSH, rewriteHTTPS:
+		return nil
+	default:
+		return errs.Newf("invalid remote form %q; must be %s or %s", rewriteRemote, rewriteSSH, rewriteHTTPS)
+	}
+}
+
+// rewriteEnv returns the environment variables that have git use the origin URL of the repo in the form given with
+// --rewrite-remote, by way of url.<base>.insteadOf. The repo's configuration is left untouched. Returns nil if the URL
+// needs no rewriting.
+func (r *repo) rewriteEnv() []string {
+	if rewriteRemote == "" {
+		return nil
+	}
+	from := r.remoteURL()
+	to := rewriteURL(from, rewriteRemote)
+	if to == "" || to == from {
+		return nil
+	}
+	// Configuration given this way is numbered, so any already present in the environment must be kept ahead of it
+	count, err := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	if err != nil || count < 0 {
+		count = 0
+	}
+	return []string{
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=url.%s.insteadOf", count, to),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", count, from),
+		fmt.Sprintf("GIT_CONFIG_COUNT=%d", count+1),
+	}
+}
+
+// remoteURL returns the URL of the repo's origin, or for repos that haven't been checked out yet, the URL they would be
+// cloned from.
+func (r *repo) remoteURL() string {
+	if r.missing {
+		return r.url
+	}
+	return originURL(r.path)
+}
+
+// reachedURL returns the URL at which git will reach the repo's origin, which differs from the configured one when it
+// is rewritten by --rewrite-remote.
+func (r *repo) reachedURL() string {
+	u := r.remoteURL()
+	if rewriteRemote != "" {
+		if to := rewriteURL(u, rewriteRemote); to != "" {
+			return to
+		}
+	}
+	return u
+}
+
+// rewriteURL returns url, a git remote URL, in the given form: the scp-like syntax of SSH, e.g. git@host:org/repo.git,
+// or HTTPS, e.g. https://host/org/repo.git. Any port is dropped, as it won't apply to the other protocol. Returns an
+// empty string for URLs that are neither SSH nor HTTP(S), such as local paths.
+func rewriteURL(url, form string) string {
+	url = strings.TrimSpace(url)
+	if sshHost(url) == "" && !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return ""
+	}
+	host, rest, found := strings.Cut(strings.TrimSuffix(normalizeRemoteURL(url), "/"), "/")
+	if !found || host == "" {
+		return ""
+	}
+	if strings.HasSuffix(strings.TrimSuffix(url, "/"), ".git") {
+		rest += ".git"
+	}
+	if form == rewriteSSH {
+		return "git@" + host + ":" + rest
+	}
+	return "https://" + host + "/" + rest
+}
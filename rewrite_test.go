@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/richardwilkes/toolbox/check"
+)
+
+func TestRewriteURL(t *testing.T) {
+	for _, one := range []struct {
+		url   string
+		ssh   string
+		https string
+	}{
+		{
+			url:   "git@github.com:org/repo.git",
+			ssh:   "git@github.com:org/repo.git",
+			https: "https://github.com/org/repo.git",
+		},
+		{url: "https://github.com/org/repo", ssh: "git@github.com:org/repo", https: "https://github.com/org/repo"},
+		{
+			url:   "https://user@github.com/org/repo.git/",
+			ssh:   "git@github.com:org/repo.git",
+			https: "https://github.com/org/repo.git",
+		},
+		{url: "http://example.com/a/b/c.git", ssh: "git@example.com:a/b/c.git", https: "https://example.com/a/b/c.git"},
+		{
+			url:   "ssh://git@gitlab.example.com:2222/group/repo.git",
+			ssh:   "git@gitlab.example.com:group/repo.git",
+			https: "https://gitlab.example.com/group/repo.git",
+		},
+		{url: "/srv/git/repo.git"},
+		{url: "../up.git"},
+		{url: "file:///srv/git/repo.git"},
+		{url: "git://example.com/repo.git"},
+		{url: "https://example.com"},
+		{url: ""},
+	} {
+		check.Equal(t, one.ssh, rewriteURL(one.url, rewriteSSH), one.url)
+		check.Equal(t, one.https, rewriteURL(one.url, rewriteHTTPS), one.url)
+	}
+}
+
+func TestRewriteEnv(t *testing.T) {
+	saved := rewriteRemote
+	defer func() { rewriteRemote = saved }()
+	r := &repo{location: &location{url: "git@github.com:org/repo.git", missing: true}}
+
+	rewriteRemote = ""
+	check.Equal(t, []string(nil), r.rewriteEnv())
+
+	// No rewriting is needed when the URL is already in the requested form
+	rewriteRemote = rewriteSSH
+	check.Equal(t, []string(nil), r.rewriteEnv())
+
+	rewriteRemote = rewriteHTTPS
+	t.Setenv("GIT_CONFIG_COUNT", "")
+	check.Equal(t, []string{
+		"GIT_CONFIG_KEY_0=url.https://github.com/org/repo.git.insteadOf",
+		"GIT_CONFIG_VALUE_0=git@github.com:org/repo.git",
+		"GIT_CONFIG_COUNT=1",
+	}, r.rewriteEnv())
+
+	// Configuration already given through the environment is kept ahead of the rewrite
+	t.Setenv("GIT_CONFIG_COUNT", "2")
+	check.Equal(t, []string{
+		"GIT_CONFIG_KEY_2=url.https://github.com/org/repo.git.insteadOf",
+		"GIT_CONFIG_VALUE_2=git@github.com:org/repo.git",
+		"GIT_CONFIG_COUNT=3",
+	}, r.rewriteEnv())
+
+	r.url = "/srv/git/repo.git"
+	check.Equal(t, []string(nil), r.rewriteEnv())
+}
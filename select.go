@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/richardwilkes/toolbox/atexit"
+	"github.com/richardwilkes/toolbox/errs"
+	rawterm "golang.org/x/term"
+)
+
+var (
+	selectRepos bool
+	// chosen holds the repos picked with --select. In watch mode, the choice made for the first run applies to all of
+	// them.
+	chosen map[string]bool
+)
+
+// selectionState holds the state of the list presented by --select.
+type selectionState struct {
+	names       []string
+	checked     []bool
+	selected    int
+	top         int
+	interrupted bool // true if the selection was cancelled with Ctrl-C
+	width       int
+	height      int
+}
+
+// chooseRepos returns the subset of the repos in list that were picked with --select, presenting the choice if it
+// hasn't been made yet. Without --select, list is returned as is.
+func chooseRepos(list []string, root string) ([]string, error) {
+	if !selectRepos {
+		return list, nil
+	}
+	if chosen == nil {
+		if !isTerminal(os.Stdout) || !isTerminal(os.Stdin) {
+			return nil, errs.New("--select requires a terminal")
+		}
+		s := &selectionState{
+			names:   make([]string, len(list)),
+			checked: make([]bool, len(list)),
+		}
+		for i, p := range list {
+			s.names[i] = displayName(root, p)
+			s.checked[i] = true
+		}
+		ok, err := s.run()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			if s.interrupted {
+				atexit.Exit(exitInterrupted)
+			}
+			atexit.Exit(0)
+		}
+		chosen = make(map[string]bool)
+		for i, p := range list {
+			if s.checked[i] {
+				chosen[p] = true
+			}
+		}
+	}
+	filtered := make([]string, 0, len(list))
+	for _, p := range list {
+		if chosen[p] {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered, nil
+}
+
+// run presents the list until the user confirms or cancels the selection. Returns true if it was confirmed.
+func (s *selectionState) run() (bool, error) {
+	fd := int(os.Stdin.Fd())
+	oldState, err := rawterm.MakeRaw(fd)
+	if err != nil {
+		return false, errs.Wrap(err)
+	}
+	defer func() {
+		fmt.Print(leaveAltScreen)
+		rawterm.Restore(fd, oldState)
+	}()
+	fmt.Print(enterAltScreen)
+	buf := make([]byte, 32)
+	for {
+		s.width, s.height = terminalSize()
+		s.render()
+		var n int
+		if n, err = os.Stdin.Read(buf); err != nil {
+			return false, errs.Wrap(err)
+		}
+		switch string(buf[:n]) {
+		case "\x03":
+			s.interrupted = true
+			return false, nil
+		case "\x1b", "q":
+			return false, nil
+		case "\r", "\n":
+			return true, nil
+		case "\x1b[A", "\x1bOA", "k":
+			s.selected--
+		case "\x1b[B", "\x1bOB", "j":
+			s.selected++
+		case "\x1b[5~", "b":
+			s.selected -= max(s.listHeight()-1, 1)
+		case "\x1b[6~":
+			s.selected += max(s.listHeight()-1, 1)
+		case "g":
+			s.selected = 0
+		case "G":
+			s.selected = len(s.names)
+		case " ", "x":
+			if s.selected >= 0 && s.selected < len(s.checked) {
+				s.checked[s.selected] = !s.checked[s.selected]
+			}
+		case "a":
+			s.checkAll(true)
+		case "n":
+			s.checkAll(false)
+		}
+	}
+}
+
+func (s *selectionState) checkAll(checked bool) {
+	for i := range s.checked {
+		s.checked[i] = checked
+	}
+}
+
+// listHeight returns the number of lines available between the header and the footer.
+func (s *selectionState) listHeight() int {
+	return max(s.height-2, 1)
+}
+
+func (s *selectionState) render() {
+	var buffer bytes.Buffer
+	t := newANSI(&buffer)
+	t.Reset()
+	t.Clear()
+	count := 0
+	for _, checked := range s.checked {
+		if checked {
+			count++
+		}
+	}
+	height := s.listHeight()
+	s.selected = max(min(s.selected, len(s.names)-1), 0)
+	if s.selected < s.top {
+		s.top = s.selected
+	} else if s.selected >= s.top+height {
+		s.top = s.selected - height + 1
+	}
+	t.Position(1, 1)
+	fmt.Fprint(t, reverseVideo+pad(fmt.Sprintf("Select the repos to process — %d of %d selected", count, len(s.names)),
+		s.width))
+	t.Reset()
+	for i := s.top; i < min(s.top+height, len(s.names)); i++ {
+		mark := "[ ]"
+		if s.checked[i] {
+			mark = "[x]"
+		}
+		t.Position(i-s.top+2, 1)
+		line := mark + " " + s.names[i]
+		if i == s.selected {
+			fmt.Fprint(t, reverseVideo+pad(line, s.width))
+			t.Reset()
+		} else {
+			fmt.Fprint(t, truncate(line, s.width))
+		}
+	}
+	t.Position(s.height, 1)
+	fmt.Fprint(t, reverseVideo+pad("↑/↓ move  space toggle  a all  n none  enter start  q cancel", s.width))
+	t.Reset()
+	os.Stdout.Write(buffer.Bytes())
+}
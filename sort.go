@@ -0,0 +1,108 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/errs"
+	"github.com/richardwilkes/toolbox/txt"
+)
+
+const (
+	sortName     = "name"
+	sortStatus   = "status"
+	sortDuration = "duration"
+	sortMTime    = "mtime"
+	sortSize     = "size"
+)
+
+var sortBy string
+
+func validateSortBy() error {
+	switch sortBy {
+	case "", sortName, sortStatus, sortDuration, sortMTime, sortSize:
+		return nil
+	default:
+		return errs.Newf("invalid sort order %q; must be one of %s, %s, %s, %s, or %s", sortBy, sortName, sortStatus,
+			sortDuration, sortMTime, sortSize)
+	}
+}
+
+// recordCommitTime records when the repo's most recent commit was made, for sorting by mtime.
+func (r *repo) recordCommitTime() {
+	if out, err := r.git("log", "-1", "--format=%ct"); err == nil {
+		if secs, parseErr := strconv.ParseInt(out, 10, 64); parseErr == nil {
+			r.committed = time.Unix(secs, 0)
+		}
+	}
+}
+
+// severity returns the rank of an outcome when sorting by status, with those most in need of attention first.
+func severity(o multirepo.Outcome) int {
+	switch o {
+	case multirepo.Failed, multirepo.Aborted, multirepo.TimedOut:
+		return 0
+	case multirepo.Skipped:
+		return 1
+	case multirepo.Updated:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// sortRepos returns the repos in the order requested by --sort, falling back to their names for those that are
+// otherwise equal. Without --sort, they are returned as is.
+func sortRepos(repos []*repo, root string) []*repo {
+	if sortBy == "" {
+		return repos
+	}
+	sorted := slices.Clone(repos)
+	slices.SortStableFunc(sorted, func(a, b *repo) int {
+		var c int
+		switch sortBy {
+		case sortStatus:
+			c = cmp.Compare(severity(a.result.Outcome), severity(b.result.Outcome))
+		case sortDuration:
+			c = cmp.Compare(b.result.Duration, a.result.Duration)
+		case sortMTime:
+			c = b.committed.Compare(a.committed)
+		case sortSize:
+			c = cmp.Compare(b.result.WorktreeSize+b.result.GitSize, a.result.WorktreeSize+a.result.GitSize)
+		default:
+		}
+		if c != 0 {
+			return c
+		}
+		nameA := displayName(root, a.path)
+		nameB := displayName(root, b.path)
+		switch {
+		case txt.NaturalLess(nameA, nameB, true):
+			return -1
+		case txt.NaturalLess(nameB, nameA, true):
+			return 1
+		default:
+			return 0
+		}
+	})
+	return sorted
+}
+
+// printDeferred prints the rows held back by processPlainMsgs in the order of repos, repeating group headers as needed.
+func printDeferred(repos []*repo, deferred map[int]string) {
+	lastHeading := 0
+	for _, r := range repos {
+		if r.quiet() {
+			continue
+		}
+		if r.heading != 0 && r.heading != lastHeading {
+			fmt.Println(deferred[r.heading])
+			lastHeading = r.heading
+		}
+		fmt.Println(deferred[r.row])
+	}
+}
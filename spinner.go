@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/richardwilkes/toolbox/xio/term"
+)
+
+const spinnerInterval = 100 * time.Millisecond
+
+// spinnerFrames are shown in turn beside each repo that is still being processed, along with how long it has been
+// running, so that one that is working can be told apart from one that has hung.
+var spinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+// spinner returns the segment to display at the end of a row whose repo began processing at start.
+func spinner(row int, segments []*msgInfo, start, now time.Time) *msgInfo {
+	elapsed := now.Sub(start)
+	return &msgInfo{
+		msg: fmt.Sprintf("%c %s", spinnerFrames[int(elapsed/spinnerInterval)%len(spinnerFrames)],
+			elapsed.Truncate(time.Second)),
+		row:   row,
+		col:   utf8.RuneCountInString(composeRow(segments)) + 2,
+		color: textColor,
+		style: term.Normal,
+	}
+}
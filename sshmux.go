@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/richardwilkes/toolbox/errs"
+)
+
+// sshMultiplex shares a single SSH connection to each host among all of the repos using it.
+var sshMultiplex bool
+
+// sshHosts holds, for each SSH host, a channel that is closed once the first repo to use it has finished its first git
+// command that reaches the remote. By then, that repo has established the connection the others share, so they needn't
+// each set up their own.
+var sshHosts struct {
+	lock  sync.Mutex
+	gates map[string]chan struct{}
+}
+
+// startSSHMultiplexing arranges for ssh, when run by git, to share its connections through control sockets. These
+// persist briefly after the last use, so that runs in quick succession, such as with --watch, can reuse them as well.
+// This takes precedence over any core.sshCommand configured for the repos, though an ssh command given by
+// GIT_SSH_COMMAND is retained.
+func startSSHMultiplexing() error {
+	if !sshMultiplex {
+		return nil
+	}
+	if runtime.GOOS == "windows" {
+		return errs.New("--ssh-multiplex is not supported on Windows, whose ssh can't share connections")
+	}
+	dir := filepath.Join(os.TempDir(), fmt.Sprintf("gp-ssh-%d", os.Getuid()))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return errs.NewWithCause("unable to create "+dir, err)
+	}
+	ssh := os.Getenv("GIT_SSH_COMMAND")
+	if ssh == "" {
+		ssh = "ssh"
+	}
+	// %C is a hash of the connection's details, which keeps the socket path within the limits of the platform
+	ssh += " -o ControlMaster=auto -o ControlPersist=60 -o ControlPath='" +
+		strings.ReplaceAll(filepath.Join(dir, "%C"), "'", `'\''`) + "'"
+	prompts.env = append(prompts.env, "GIT_SSH_COMMAND="+ssh)
+	sshHosts.gates = make(map[string]chan struct{})
+	return nil
+}
+
+// awaitSSHConnection waits, if another repo is the first to use the SSH host the repo's origin is on, until that repo
+// has connected to it. The returned function must be called once the repo has been processed, in case it never did.
+func (r *repo) awaitSSHConnection() (release func()) {
+	if !sshMultiplex {
+		return func() {}
+	}
+	host := sshHost(r.reachedURL())
+	if host == "" {
+		return func() {}
+	}
+	sshHosts.lock.Lock()
+	gate, exists := sshHosts.gates[host]
+	if !exists {
+		gate = make(chan struct{})
+		sshHosts.gates[host] = gate
+	}
+	sshHosts.lock.Unlock()
+	if !exists {
+		r.releaseSSH = sync.OnceFunc(func() { close(gate) })
+		return r.releaseSSH
+	}
+	select {
+	case <-gate:
+	case <-r.ctx.Done():
+	}
+	return func() {}
+}
+
+// networkCommands are the git subcommands that reach a remote.
+var networkCommands = []string{"clone", "fetch", "ls-remote", "pull", "push", "remote"}
+
+// networkCommand returns true if args, those of a git or jj process, are for a command that reaches a remote.
+func networkCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	jj := strings.TrimSuffix(filepath.Base(args[0]), ".exe") == "jj"
+	for i := 1; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-c" || arg == "-C" || arg == "--color":
+			// These take a value
+			i++
+		case strings.HasPrefix(arg, "-"):
+		case jj && arg == "git":
+			// jj reaches remotes through its git subcommands, e.g. jj git fetch
+			jj = false
+		default:
+			return slices.Contains(networkCommands, arg)
+		}
+	}
+	return false
+}
+
+// sshHost returns the host of url, a git remote URL, should it be reached using SSH, or an empty string otherwise.
+func sshHost(url string) string {
+	url = strings.TrimSpace(url)
+	switch {
+	case strings.HasPrefix(url, "ssh://"), strings.HasPrefix(url, "git+ssh://"), strings.HasPrefix(url, "ssh+git://"):
+	case strings.Contains(url, "://"), hasDrivePrefix(url):
+		return ""
+	default:
+		// Only the scp-like syntax, e.g. user@host:path, uses SSH without saying so
+		i := strings.Index(url, ":")
+		if i < 1 || strings.Contains(url[:i], "/") {
+			return ""
+		}
+	}
+	host, _, _ := strings.Cut(normalizeRemoteURL(url), "/")
+	return host
+}
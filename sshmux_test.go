@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/richardwilkes/toolbox/check"
+)
+
+func TestNetworkCommand(t *testing.T) {
+	for _, one := range []struct {
+		args     []string
+		expected bool
+	}{
+		{args: []string{"git", "fetch", "--all", "--prune"}, expected: true},
+		{args: []string{"/usr/bin/git", "-c", "core.hooksPath=fetch", "pull", "--ff-only"}, expected: true},
+		{args: []string{"git", "-C", "/src/remote", "ls-remote", "origin"}, expected: true},
+		{args: []string{"git", "--no-pager", "push"}, expected: true},
+		{args: []string{"git", "remote", "update", "--prune"}, expected: true},
+		{args: []string{"jj", "--no-pager", "--color", "never", "git", "fetch"}, expected: true},
+		{args: []string{"git", "status", "--porcelain=v2"}},
+		{args: []string{"git", "rev-parse", "fetch"}},
+		{args: []string{"git", "-C", "fetch", "status"}},
+		{args: []string{"jj", "--no-pager", "--color", "never", "log"}},
+		{args: []string{"sh", "-c", "npm ci"}},
+		{args: []string{"git"}},
+		{},
+	} {
+		check.Equal(t, one.expected, networkCommand(one.args), "%q", one.args)
+	}
+}
+
+func TestSSHHostRewritten(t *testing.T) {
+	saved := rewriteRemote
+	defer func() { rewriteRemote = saved }()
+	r := &repo{location: &location{url: "git@github.com:org/repo.git", missing: true}}
+	rewriteRemote = ""
+	check.Equal(t, "github.com", sshHost(r.reachedURL()))
+	// git won't use SSH to reach an origin rewritten to HTTPS, so there is no connection to share
+	rewriteRemote = rewriteHTTPS
+	check.Equal(t, "", sshHost(r.reachedURL()))
+	r.url = "https://github.com/org/repo.git"
+	rewriteRemote = rewriteSSH
+	check.Equal(t, "github.com", sshHost(r.reachedURL()))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// skipStale, if positive, causes repos with no activity for at least this long to be skipped.
+var skipStale time.Duration
+
+// lastActivity returns the most recent time the repo is known to have changed: its latest commit, the latest commit
+// fetched for its upstream, or the last change to its index, which is updated by checkouts, commits, and staging.
+func (r *repo) lastActivity() time.Time {
+	var latest time.Time
+	out, err := r.gitActual("log", "-1", "--format=%ct", "HEAD", "@{upstream}")
+	if err != nil {
+		// Most likely there's no upstream
+		out, err = r.gitActual("log", "-1", "--format=%ct", "HEAD")
+	}
+	if err == nil {
+		if secs, parseErr := strconv.ParseInt(strings.TrimSpace(out), 10, 64); parseErr == nil {
+			latest = time.Unix(secs, 0)
+		}
+	}
+	if index, indexErr := r.gitActual("rev-parse", "--git-path", "index"); indexErr == nil {
+		if !filepath.IsAbs(index) {
+			index = filepath.Join(r.path, index)
+		}
+		if fi, statErr := os.Stat(index); statErr == nil && fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest
+}
+
+// isDormant returns true if --skip-stale is in effect and the repo has had no activity within it.
+func (r *repo) isDormant() bool {
+	if skipStale <= 0 {
+		return false
+	}
+	last := r.lastActivity()
+	return !last.IsZero() && time.Since(last) > skipStale
+}
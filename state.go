@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	autoAbort bool
+	unshallow bool
+)
+
+// isShallow returns true if the repo is a shallow clone, lacking some of its history.
+func (r *repo) isShallow() bool {
+	out, err := r.gitActual("rev-parse", "--is-shallow-repository")
+	return err == nil && out == "true"
+}
+
+// inProgressMarkers maps the files and directories git creates within its directory while an operation is unfinished to
+// the name of the operation, in the order they should be checked.
+var inProgressMarkers = []struct {
+	name string
+	op   string
+}{
+	{name: "rebase-merge", op: "rebase"},
+	{name: "rebase-apply", op: "rebase"},
+	{name: "MERGE_HEAD", op: "merge"},
+	{name: "CHERRY_PICK_HEAD", op: "cherry-pick"},
+	{name: "REVERT_HEAD", op: "revert"},
+	{name: "BISECT_LOG", op: "bisect"},
+}
+
+// operationInProgress returns the name of the operation left unfinished in the repo, such as a rebase or bisect, if
+// any. The locations of the files that indicate them are obtained from git, so that worktrees are handled correctly.
+func (r *repo) operationInProgress() string {
+	args := []string{"rev-parse"}
+	for _, marker := range inProgressMarkers {
+		args = append(args, "--git-path", marker.name)
+	}
+	out, err := r.gitActual(args...)
+	if err != nil {
+		return ""
+	}
+	paths := strings.Split(out, "\n")
+	for i, marker := range inProgressMarkers {
+		if i >= len(paths) {
+			break
+		}
+		p := paths[i]
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(r.path, p)
+		}
+		if _, err = os.Stat(p); err == nil {
+			return marker.op
+		}
+	}
+	return ""
+}
+
+// abortFailedPull aborts the merge or rebase that a failed pull left in progress, if any, noting that the repo was
+// restored. The repo must already have been marked as failed.
+func (r *repo) abortFailedPull() {
+	op := r.operationInProgress()
+	if op != "merge" && op != "rebase" {
+		return
+	}
+	if _, err := r.gitActual(op, "--abort"); err != nil {
+		r.fail(r.result.Status+"; "+op+" --abort failed", err)
+		return
+	}
+	r.annotate(op + " aborted, repo restored")
+}
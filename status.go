@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/cmdline"
+)
+
+type statusCmd struct{}
+
+func (c *statusCmd) Name() string {
+	return "status"
+}
+
+func (c *statusCmd) Usage() string {
+	return "Shows the branch, the number of modified and untracked files, the number of stashes, and how far the " +
+		"branch is ahead of or behind its upstream as of the last fetch, without pulling or contacting any remotes."
+}
+
+func (c *statusCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	locking = false
+	recording = false
+	addCommonOptions(cl)
+	stale := defaults.Stale
+	cl.NewOption(&overrideValue{
+		GeneralValue: cmdline.GeneralValue{Value: &stale},
+		apply:        func() { overrides.Stale = stale },
+	}).SetName("stale").SetArg("duration").
+		SetUsage("Highlight the repos whose most recent commit is older than this, such as 2160h for 90 days. Zero " +
+			"means repos are never considered stale")
+	staleStash := defaults.StaleStash
+	cl.NewOption(&overrideValue{
+		GeneralValue: cmdline.GeneralValue{Value: &staleStash},
+		apply:        func() { overrides.StaleStash = staleStash },
+	}).SetName("stale-stash").SetArg("duration").
+		SetUsage("Also count the stashes older than this, such as 720h for 30 days. Zero means " +
+			"stashes are never considered stale")
+	run(cl.Parse(args), statusRepo)
+	return nil
+}
+
+func statusRepo(r *repo) {
+	if !r.showBranch() {
+		return
+	}
+	local, err := r.localChanges()
+	if err != nil {
+		r.fail("error", err)
+		return
+	}
+	var stashes string
+	if stashes, err = r.git("stash", "list", "--format=%ct"); err != nil {
+		r.fail("error", err)
+		return
+	}
+	r.trackUpstream()
+	r.recordLastCommit()
+	var parts []string
+	if modified := local.staged + local.unstaged; modified != 0 {
+		parts = append(parts, fmt.Sprintf("%d modified", modified))
+	}
+	if local.untracked != 0 {
+		parts = append(parts, fmt.Sprintf("%d untracked", local.untracked))
+	}
+	if r.isShallow() {
+		parts = append(parts, "shallow")
+	}
+	if stashes != "" {
+		times := strings.Split(stashes, "\n")
+		r.result.Stashes = len(times)
+		parts = append(parts, fmt.Sprintf("%d %s", len(times), plural(len(times), "stash", "stashes")))
+		if r.cfg.StaleStash > 0 {
+			cutoff := time.Now().Add(-r.cfg.StaleStash).Unix()
+			for _, t := range times {
+				if secs, parseErr := strconv.ParseInt(t, 10, 64); parseErr == nil && secs < cutoff {
+					r.result.Stale++
+				}
+			}
+			if r.result.Stale != 0 {
+				parts = append(parts, fmt.Sprintf("%d older than %s", r.result.Stale, formatAge(r.cfg.StaleStash)))
+			}
+		}
+	}
+	if r.result.StaleHead {
+		parts = append(parts, "stale")
+	}
+	switch {
+	case len(parts) != 0:
+		r.notice(strings.Join(parts, ", "))
+	case r.result.Ahead != 0 || r.result.Behind != 0:
+		r.notice("clean")
+	default:
+		r.succeeded("clean")
+	}
+	if last := r.result.LastCommit; last != nil {
+		r.annotate("last: " + formatSince(time.Since(last.Time)) + " ago by " + last.Author + ": " + last.Subject)
+	}
+}
+
+// recordLastCommit records the time, author, and subject of the repo's most recent commit, if it has one, and whether
+// that makes the repo stale.
+func (r *repo) recordLastCommit() {
+	out, err := r.git("log", "-1", "--format=%ct%x00%an%x00%s")
+	if err != nil {
+		return
+	}
+	fields := strings.SplitN(out, "\x00", 3)
+	if len(fields) != 3 {
+		return
+	}
+	secs, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return
+	}
+	r.committed = time.Unix(secs, 0)
+	r.result.LastCommit = &multirepo.CommitInfo{Time: r.committed, Author: fields[1], Subject: fields[2]}
+	r.result.StaleHead = r.cfg.Stale > 0 && time.Since(r.committed) > r.cfg.Stale
+}
+
+// formatAge returns age as a number of days, if it is a whole number of them, or as a duration otherwise.
+func formatAge(age time.Duration) string {
+	if age%(24*time.Hour) == 0 {
+		days := int(age / (24 * time.Hour))
+		return fmt.Sprintf("%d %s", days, plural(days, "day", "days"))
+	}
+	return age.String()
+}
+
+// formatSince returns elapsed in a compact form, using the largest unit of which there are at least a couple.
+func formatSince(elapsed time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case elapsed < 2*time.Minute:
+		return fmt.Sprintf("%ds", int(elapsed/time.Second))
+	case elapsed < 2*time.Hour:
+		return fmt.Sprintf("%dm", int(elapsed/time.Minute))
+	case elapsed < 2*day:
+		return fmt.Sprintf("%dh", int(elapsed/time.Hour))
+	case elapsed < 730*day:
+		return fmt.Sprintf("%dd", int(elapsed/day))
+	default:
+		return fmt.Sprintf("%dy", int(elapsed/(365*day)))
+	}
+}
+
+func plural(count int, singular, multiple string) string {
+	if count == 1 {
+		return singular
+	}
+	return multiple
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/xio/term"
+)
+
+// printTranscripts prints the output of the commands run for each repo that failed or was updated.
+func printTranscripts(t *ansi, repos []*repo, root string) {
+	for _, r := range repos {
+		switch r.result.Outcome {
+		case multirepo.Updated, multirepo.Failed, multirepo.Aborted, multirepo.TimedOut:
+		default:
+			continue
+		}
+		if len(r.transcript) != 0 {
+			printDetails(t, displayName(root, r.path)+": "+r.result.Status, r.transcript, "")
+		}
+	}
+}
+
+// printIncoming prints the commits and diffstat brought in by pulling each repo, where they were recorded.
+func printIncoming(t *ansi, repos []*repo, root string) {
+	for _, r := range repos {
+		lines := r.result.Commits
+		if len(lines) != 0 && len(r.result.Diffstat) != 0 {
+			lines = append(slices.Clone(lines), "")
+		}
+		lines = append(lines, r.result.Diffstat...)
+		if len(lines) != 0 {
+			printDetails(t, displayName(root, r.path)+": "+r.result.Changes, lines, "  ")
+		}
+	}
+}
+
+// printDetails prints a blank line and the heading, followed by each of the lines with indent before it.
+func printDetails(t *ansi, heading string, lines []string, indent string) {
+	fmt.Println()
+	if t != nil {
+		t.Foreground(textColor, term.Bold)
+	}
+	fmt.Println(heading)
+	if t != nil {
+		t.Reset()
+	}
+	for _, line := range lines {
+		if line != "" {
+			line = indent + line
+		}
+		fmt.Println(line)
+	}
+}
+
+// summaryOutcomes lists the outcomes in the order in which the number of repos with each is reported.
+var summaryOutcomes = []multirepo.Outcome{
+	multirepo.Updated, multirepo.Unchanged, multirepo.Skipped, multirepo.Ignored, multirepo.Failed, multirepo.Aborted,
+	multirepo.TimedOut,
+}
+
+// printSummary prints the number of repos with each outcome and the total time taken, followed by the names of any
+// repos that failed and, if requested, the slowest repos. If t is not nil, it is used to highlight the failures.
+func printSummary(t *ansi, repos []*repo, root string, elapsed time.Duration) {
+	counts := make(map[multirepo.Outcome]int)
+	// Failures are grouped by their reason, with those whose reason is unknown under the empty string
+	failures := make(map[string][]string)
+	var reasons []string
+	for _, r := range repos {
+		counts[r.result.Outcome]++
+		switch r.result.Outcome {
+		case multirepo.Failed, multirepo.Aborted, multirepo.TimedOut:
+			reason := r.result.Reason
+			if _, exists := failures[reason]; !exists {
+				reasons = append(reasons, reason)
+			}
+			failures[reason] = append(failures[reason], displayName(root, r.path))
+		default:
+		}
+	}
+	slices.Sort(reasons)
+	parts := make([]string, 0, len(counts))
+	for _, o := range summaryOutcomes {
+		if counts[o] != 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[o], o))
+		}
+	}
+	fmt.Println()
+	fmt.Printf("%d %s in %s", len(repos), plural(len(repos), "repo", "repos"), elapsed.Round(100*time.Millisecond))
+	if len(parts) != 0 {
+		fmt.Print(": " + strings.Join(parts, ", "))
+	}
+	fmt.Println()
+	if len(failures) != 0 {
+		if t != nil {
+			t.Foreground(failedColor, term.Bold)
+		}
+		for _, reason := range reasons {
+			label := "Failed"
+			if reason != "" {
+				label += " (" + reason + ")"
+			}
+			fmt.Println(label + ": " + strings.Join(failures[reason], ", "))
+		}
+		if t != nil {
+			t.Reset()
+		}
+	}
+	if slowest > 0 {
+		byDuration := slices.Clone(repos)
+		slices.SortStableFunc(byDuration, func(a, b *repo) int {
+			return cmp.Compare(b.result.Duration, a.result.Duration)
+		})
+		list := make([]string, 0, slowest)
+		for _, r := range byDuration[:min(slowest, len(byDuration))] {
+			list = append(list, fmt.Sprintf("%s (%.1fs)", displayName(root, r.path), r.result.Duration))
+		}
+		fmt.Println("Slowest: " + strings.Join(list, ", "))
+	}
+}
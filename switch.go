@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/richardwilkes/toolbox/cmdline"
+)
+
+type switchCmd struct {
+	branch    string
+	toDefault bool
+}
+
+func (c *switchCmd) Name() string {
+	return "switch"
+}
+
+func (c *switchCmd) Usage() string {
+	return "Switches every clean repo to the named branch, or to its default branch, skipping those with local changes."
+}
+
+func (c *switchCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	addCommonOptions(cl)
+	cl.UsageSuffix = "<branch> " + pathsUsage
+	cl.NewGeneralOption(&c.toDefault).SetName("default").
+		SetUsage("Switch to each repo's default branch, as determined by origin/HEAD, rather than a named branch")
+	addDryRunOption(cl)
+	paths := cl.Parse(args)
+	if !c.toDefault {
+		if len(paths) == 0 {
+			cl.FatalMsg("A branch or --default must be specified")
+		}
+		c.branch = paths[0]
+		paths = paths[1:]
+	}
+	run(paths, c.switchRepo)
+	return nil
+}
+
+func (c *switchCmd) switchRepo(r *repo) {
+	if !r.showBranch() {
+		return
+	}
+	branch := c.branch
+	if c.toDefault {
+		def, err := r.defaultBranch()
+		if err != nil {
+			r.skipState("no default branch")
+			return
+		}
+		_, branch, _ = strings.Cut(def, "/")
+	}
+	if r.result.Branch == branch {
+		r.succeeded("already on " + branch)
+		return
+	}
+	local, err := r.localChanges()
+	if err != nil {
+		r.fail("skipped due to error", err)
+		return
+	}
+	if local.total() != 0 {
+		r.skip("changes")
+		return
+	}
+	if dryRun {
+		r.notice("would switch to " + branch)
+		return
+	}
+	from := r.result.Branch
+	var found bool
+	if found, err = r.switchTo(branch); err != nil {
+		r.fail("failed to switch to "+branch, err)
+		return
+	}
+	if !found {
+		r.skipState("no branch " + branch)
+		return
+	}
+	if from == "" {
+		from = "detached HEAD"
+	}
+	r.changed("switched from " + from)
+}
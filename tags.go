@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/richardwilkes/toolbox/cmdline"
+)
+
+// syncTags causes fetches and pulls to update all tags, replacing any local ones that differ from the remote's.
+var syncTags bool
+
+func addTagsOption(cl *cmdline.CmdLine) {
+	cl.NewGeneralOption(&syncTags).SetName("tags").
+		SetUsage("Fetch all tags, replacing local tags that have been moved on the remote, rather than only those on " +
+			"the branches fetched")
+}
+
+// tagArgs returns the arguments to add to a fetch or pull to honor --tags.
+func tagArgs() []string {
+	if syncTags {
+		return []string{"--tags", "--force"}
+	}
+	return nil
+}
+
+type tagsCmd struct {
+	pattern string
+	fetch   bool
+}
+
+func (c *tagsCmd) Name() string {
+	return "tags"
+}
+
+func (c *tagsCmd) Usage() string {
+	return "Shows the most recently created tag matching a pattern, such as \"v*\", in each repo."
+}
+
+func (c *tagsCmd) Run(cl *cmdline.CmdLine, args []string) error {
+	cl.Description = c.Usage()
+	addCommonOptions(cl)
+	cl.UsageSuffix = "<pattern> " + pathsUsage
+	cl.NewGeneralOption(&c.fetch).SetName("fetch").
+		SetUsage("Fetch the tags from all remotes first, replacing local tags that have been moved, rather than " +
+			"using those already present")
+	paths := cl.Parse(args)
+	if len(paths) == 0 {
+		cl.FatalMsg("A tag pattern must be specified; use \"*\" for all tags")
+	}
+	c.pattern = paths[0]
+	// Without fetching, the repos are only inspected
+	locking = c.fetch
+	recording = c.fetch
+	run(paths[1:], c.tagsRepo)
+	return nil
+}
+
+func (c *tagsCmd) tagsRepo(r *repo) {
+	if !r.showBranch() {
+		return
+	}
+	if c.fetch {
+		if _, err := r.git("fetch", "--all", "--tags", "--force"); err != nil {
+			r.fail("failed to fetch", err)
+			return
+		}
+	}
+	out, err := r.git("tag", "--list", "--sort=-v:refname", "--sort=-creatordate",
+		"--format=%(refname:short) %(creatordate:short)", c.pattern)
+	if err != nil {
+		r.fail("error", err)
+		return
+	}
+	if out == "" {
+		r.skip("no tags matching " + c.pattern)
+		return
+	}
+	latest, _, _ := strings.Cut(out, "\n")
+	name, date, _ := strings.Cut(latest, " ")
+	r.notice(name + " (" + date + ")")
+}
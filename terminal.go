@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// enableVirtualTerminal returns true, as terminals elsewhere interpret ANSI escape sequences without being asked.
+func enableVirtualTerminal(_ *os.File) bool {
+	return true
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// enableVirtualTerminal asks the console f is attached to to interpret ANSI escape sequences, which Windows consoles
+// only do once virtual terminal processing has been enabled. Returns false if f isn't a console or the console predates
+// support for it, in which case plain output must be used.
+func enableVirtualTerminal(f *os.File) bool {
+	h := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(h, &mode); err != nil {
+		return false
+	}
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+		return true
+	}
+	return windows.SetConsoleMode(h, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/richardwilkes/toolbox/errs"
+	"github.com/richardwilkes/toolbox/xio/term"
+)
+
+// The colors used for each kind of output. Those for text, unchanged repos, and ignored repos are adjusted for dark
+// backgrounds, after which any set in the configuration are applied.
+var (
+	textColor      = term.Black
+	unchangedColor = term.Blue
+	updatedColor   = term.Magenta
+	noticeColor    = term.Magenta
+	skippedColor   = term.Magenta
+	stateColor     = term.Yellow
+	failedColor    = term.Red
+	ignoredColor   = term.Black
+)
+
+// palette holds the colors from the configuration, keyed by the kind of output they apply to.
+var palette = make(map[string]string)
+
+var themeOnce sync.Once
+
+var colorNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// paletteEntries returns the color variable for each of the keys that may be used in the configuration's colors.
+func paletteEntries() map[string]*term.Color {
+	return map[string]*term.Color{
+		"text":      &textColor,
+		"unchanged": &unchangedColor,
+		"updated":   &updatedColor,
+		"notice":    &noticeColor,
+		"skipped":   &skippedColor,
+		"state":     &stateColor,
+		"failed":    &failedColor,
+		"ignored":   &ignoredColor,
+	}
+}
+
+func validatePalette(colors map[string]string) error {
+	entries := paletteEntries()
+	for key, name := range colors {
+		if _, ok := entries[key]; !ok {
+			return errs.Newf("unknown color key %q", key)
+		}
+		if !slices.Contains(colorNames, name) {
+			return errs.Newf("invalid color %q for %s; must be one of %s", name, key, strings.Join(colorNames, ", "))
+		}
+	}
+	return nil
+}
+
+// adjustColorsForTheme picks the colors to use, based upon the background of the terminal and the configuration. This
+// is only done once, as querying the terminal requires interacting with it.
+func adjustColorsForTheme() {
+	themeOnce.Do(func() {
+		if darkBackground() {
+			textColor = term.White
+			unchangedColor = term.Cyan
+			ignoredColor = term.White
+		}
+		entries := paletteEntries()
+		for key, name := range palette {
+			*entries[key] = term.Color(slices.Index(colorNames, name))
+		}
+	})
+}
+
+// darkBackground returns true if the terminal appears to have a dark background. The COLORFGBG variable set by some
+// terminals is consulted first, then the terminal itself is asked, and finally the system's appearance setting is
+// used, where one can be found.
+func darkBackground() bool {
+	if dark, ok := colorFGBGIsDark(os.Getenv("COLORFGBG")); ok {
+		return dark
+	}
+	if useColor() && isTerminal(os.Stdout) {
+		if dark, ok := queryBackground(); ok {
+			return dark
+		}
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		out, err := exec.Command("defaults", "read", "-g", "AppleInterfaceStyle").Output()
+		return err == nil && bytes.HasPrefix(out, []byte("Dark"))
+	case "windows":
+		out, err := exec.Command("reg", "query", `HKCU\Software\Microsoft\Windows\CurrentVersion\Themes\Personalize`,
+			"/v", "AppsUseLightTheme").Output()
+		return err == nil && bytes.Contains(out, []byte("0x0"))
+	default:
+		return false
+	}
+}
+
+// colorFGBGIsDark interprets the COLORFGBG variable, which holds the foreground and background colors as ANSI color
+// numbers separated by semicolons, with the background last.
+func colorFGBGIsDark(value string) (dark, ok bool) {
+	if value == "" {
+		return false, false
+	}
+	parts := strings.Split(value, ";")
+	bg, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		return false, false
+	}
+	// 7 is light gray and 9 through 15 are the bright colors; the rest are dark
+	return bg < 7 || bg == 8, true
+}
+
+// oscColorIsDark interprets the reply to an OSC 11 background color query, which contains the color in the form
+// rgb:RRRR/GGGG/BBBB, with each component having from 1 to 4 hex digits.
+func oscColorIsDark(reply string) (dark, ok bool) {
+	i := strings.Index(reply, "rgb:")
+	if i == -1 {
+		return false, false
+	}
+	spec := strings.TrimRight(reply[i+4:], "\x07\x1b\\")
+	parts := strings.Split(spec, "/")
+	if len(parts) != 3 {
+		return false, false
+	}
+	var rgb [3]float64
+	for j, part := range parts {
+		v, err := strconv.ParseUint(part, 16, 16)
+		if err != nil || part == "" {
+			return false, false
+		}
+		rgb[j] = float64(v) / float64(uint64(1)<<(4*len(part))-1)
+	}
+	return 0.299*rgb[0]+0.587*rgb[1]+0.114*rgb[2] < 0.5, true
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/richardwilkes/gp/multirepo"
+	rawterm "golang.org/x/term"
+)
+
+var tui bool
+
+const (
+	enterAltScreen = "\x1b[?1049h\x1b[?25l"
+	leaveAltScreen = "\x1b[?25h\x1b[?1049l"
+	reverseVideo   = "\x1b[7m"
+)
+
+type tuiFilter int
+
+const (
+	showAll tuiFilter = iota
+	showFailed
+	showSkipped
+	showUpdated
+)
+
+func (f tuiFilter) String() string {
+	switch f {
+	case showFailed:
+		return "failed"
+	case showSkipped:
+		return "skipped"
+	case showUpdated:
+		return "updated"
+	default:
+		return "all"
+	}
+}
+
+func (f tuiFilter) matches(row *tuiRow) bool {
+	if row.header {
+		return f == showAll
+	}
+	switch f {
+	case showFailed:
+		return row.done &&
+			(row.outcome == multirepo.Failed || row.outcome == multirepo.Aborted || row.outcome == multirepo.TimedOut)
+	case showSkipped:
+		return row.done && row.outcome == multirepo.Skipped
+	case showUpdated:
+		return row.done && row.outcome == multirepo.Updated
+	default:
+		return true
+	}
+}
+
+// tuiRow holds what is known about a single repo within the TUI.
+type tuiRow struct {
+	segments   []*msgInfo
+	transcript []string
+	done       bool
+	outcome    multirepo.Outcome
+	started    time.Time // when the repo began processing, while it is still being processed
+	header     bool      // true if the row is the header of a group of repos, rather than a repo
+}
+
+// tuiState holds the state of the full-screen interactive display.
+type tuiState struct {
+	rows      []*tuiRow
+	filter    tuiFilter
+	selected  int // index into the filtered rows
+	top       int // first filtered row shown
+	detail    *tuiRow
+	detailTop int
+	finished  bool
+	interrupt bool // true once an interrupt has been requested
+	width     int
+	height    int
+}
+
+// processTUI is the interactive alternative to processMsgs. The repos are listed in a scrollable, filterable view and
+// the output of the commands run for any of them can be viewed by selecting it. It keeps running after all of the repos
+// have finished, until the user quits.
+func processTUI(wg *sync.WaitGroup, printer chan *msgInfo) {
+	defer wg.Done()
+	fd := int(os.Stdin.Fd())
+	oldState, err := rawterm.MakeRaw(fd)
+	if err != nil {
+		processPlainMsgs(&sync.WaitGroup{}, printer, true, nil)
+		return
+	}
+	defer func() {
+		fmt.Print(leaveAltScreen)
+		rawterm.Restore(fd, oldState)
+	}()
+	fmt.Print(enterAltScreen)
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+	defer signal.Stop(resized)
+	keys := make(chan string)
+	go readKeys(keys)
+	s := &tuiState{}
+	s.width, s.height = terminalSize()
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case m, ok := <-printer:
+			if !ok {
+				printer = nil
+				s.finished = true
+				if keys == nil {
+					return
+				}
+				break
+			}
+			s.handleMsg(m)
+		case k, ok := <-keys:
+			if !ok {
+				// Without input, nothing can dismiss the display, so carry on with the run and leave once it finishes
+				keys = nil
+				if s.finished {
+					return
+				}
+				break
+			}
+			if !s.handleKey(k) {
+				return
+			}
+		case <-resized:
+			s.width, s.height = terminalSize()
+		case <-ticker.C:
+			if !s.spinning() {
+				continue
+			}
+		}
+		// Coalesce bursts of messages into a single redraw
+		if len(printer) == 0 {
+			s.render()
+		}
+	}
+}
+
+// readKeys sends each chunk of input read from the terminal to keys. In raw mode, each chunk is a single key press or
+// escape sequence.
+func readKeys(keys chan<- string) {
+	buf := make([]byte, 32)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		keys <- string(buf[:n])
+	}
+}
+
+func (s *tuiState) handleMsg(m *msgInfo) {
+	for len(s.rows) < m.row {
+		s.rows = append(s.rows, &tuiRow{})
+	}
+	row := s.rows[m.row-1]
+	switch {
+	case m.started:
+		row.started = time.Now()
+	case m.done:
+		row.done = true
+		row.outcome = m.outcome
+		row.started = time.Time{}
+	case m.transcript:
+		row.transcript = append(row.transcript, strings.Split(m.msg, "\n")...)
+	default:
+		row.header = m.header
+		row.segments = addSegment(row.segments, m)
+	}
+}
+
+// spinning returns true if any of the repos is still being processed, so that its spinner needs to be advanced.
+func (s *tuiState) spinning() bool {
+	for _, row := range s.rows {
+		if !row.started.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// handleKey applies the key press k. Returns false if the TUI should exit.
+func (s *tuiState) handleKey(k string) bool {
+	if k == "\x03" { // Ctrl-C
+		if s.finished {
+			return false
+		}
+		// Only signal once, since a second interrupt would terminate the process with the terminal still in raw mode
+		if !s.interrupt {
+			s.interrupt = true
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				p.Signal(os.Interrupt)
+			}
+		}
+		return true
+	}
+	page := max(s.listHeight()-1, 1)
+	if s.detail != nil {
+		switch k {
+		case "\x1b", "q", "\x7f", "\x1b[D", "\x1bOD", "h":
+			s.detail = nil
+		case "\x1b[A", "\x1bOA", "k":
+			s.detailTop--
+		case "\x1b[B", "\x1bOB", "j":
+			s.detailTop++
+		case "\x1b[5~", "b":
+			s.detailTop -= page
+		case "\x1b[6~", " ":
+			s.detailTop += page
+		case "g":
+			s.detailTop = 0
+		case "G":
+			s.detailTop = len(s.detail.transcript)
+		}
+		return true
+	}
+	switch k {
+	case "q":
+		return !s.finished
+	case "\x1b[A", "\x1bOA", "k":
+		s.selected--
+	case "\x1b[B", "\x1bOB", "j":
+		s.selected++
+	case "\x1b[5~", "b":
+		s.selected -= page
+	case "\x1b[6~", " ":
+		s.selected += page
+	case "g":
+		s.selected = 0
+	case "G":
+		s.selected = len(s.rows)
+	case "a":
+		s.setFilter(showAll)
+	case "f":
+		s.setFilter(showFailed)
+	case "s":
+		s.setFilter(showSkipped)
+	case "u":
+		s.setFilter(showUpdated)
+	case "\r", "\n", "\x1b[C", "\x1bOC", "l":
+		if visible := s.visible(); s.selected >= 0 && s.selected < len(visible) && !visible[s.selected].header {
+			s.detail = visible[s.selected]
+			s.detailTop = 0
+		}
+	}
+	return true
+}
+
+func (s *tuiState) setFilter(f tuiFilter) {
+	s.filter = f
+	s.selected = 0
+	s.top = 0
+}
+
+// visible returns the rows that pass the current filter.
+func (s *tuiState) visible() []*tuiRow {
+	list := make([]*tuiRow, 0, len(s.rows))
+	for _, row := range s.rows {
+		if s.filter.matches(row) {
+			list = append(list, row)
+		}
+	}
+	return list
+}
+
+// listHeight returns the number of lines available between the header and the footer.
+func (s *tuiState) listHeight() int {
+	return max(s.height-2, 1)
+}
+
+func (s *tuiState) render() {
+	var buffer bytes.Buffer
+	t := newANSI(&buffer)
+	t.Reset()
+	t.Clear()
+	done := 0
+	total := 0
+	for _, row := range s.rows {
+		if !row.header {
+			total++
+			if row.done {
+				done++
+			}
+		}
+	}
+	progress := fmt.Sprintf("%d of %d done", done, total)
+	if s.finished {
+		progress = "finished"
+	}
+	height := s.listHeight()
+	if s.detail != nil {
+		s.header(t, composeRow(s.detail.segments))
+		lines := s.detail.transcript
+		if len(lines) == 0 {
+			lines = []string{"(no output captured yet)"}
+		}
+		s.detailTop = max(min(s.detailTop, len(lines)-height), 0)
+		t.Reset()
+		for i, line := range lines[s.detailTop:min(s.detailTop+height, len(lines))] {
+			t.Position(i+2, 1)
+			fmt.Fprint(t, truncate(line, s.width))
+		}
+		s.footer(t, "↑/↓ scroll  esc back")
+	} else {
+		s.header(t, fmt.Sprintf("gp — %s — showing %s", progress, s.filter))
+		visible := s.visible()
+		s.selected = max(min(s.selected, len(visible)-1), 0)
+		if s.selected < s.top {
+			s.top = s.selected
+		} else if s.selected >= s.top+height {
+			s.top = s.selected - height + 1
+		}
+		now := time.Now()
+		for i, row := range visible[s.top:min(s.top+height, len(visible))] {
+			line := i + 2
+			segments := row.segments
+			if !row.started.IsZero() {
+				segments = append(slices.Clip(segments), spinner(line, segments, row.started, now))
+			}
+			if s.top+i == s.selected {
+				t.Reset()
+				t.Position(line, 1)
+				fmt.Fprint(t, reverseVideo+pad(composeRow(segments), s.width))
+				continue
+			}
+			for _, m := range segments {
+				t.Foreground(m.color, m.style)
+				t.Position(line, m.col)
+				fmt.Fprint(t, truncate(firstLine(m.msg), max(s.width-m.col, 0)))
+			}
+		}
+		help := "↑/↓ move  enter details  a all  f failed  s skipped  u updated"
+		if s.finished {
+			help += "  q quit"
+		} else {
+			help += "  ctrl-c abort"
+		}
+		s.footer(t, help)
+	}
+	t.Reset()
+	os.Stdout.Write(buffer.Bytes())
+}
+
+func (s *tuiState) header(t *ansi, text string) {
+	t.Reset()
+	t.Position(1, 1)
+	fmt.Fprint(t, reverseVideo+pad(text, s.width))
+	t.Reset()
+}
+
+func (s *tuiState) footer(t *ansi, text string) {
+	t.Reset()
+	t.Position(s.height, 1)
+	fmt.Fprint(t, reverseVideo+pad(text, s.width))
+	t.Reset()
+}
+
+// pad returns text truncated or padded with spaces to exactly width runes, less one so that the terminal doesn't wrap.
+func pad(text string, width int) string {
+	width = max(width-1, 0)
+	text = truncate(text, width)
+	return text + strings.Repeat(" ", width-len([]rune(text)))
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/richardwilkes/gp/multirepo"
+	"github.com/richardwilkes/toolbox/errs"
+	"github.com/richardwilkes/toolbox/xio"
+)
+
+var (
+	webhookURL      string
+	slackWebhookURL string
+)
+
+// webhookSummary is the summary of a run posted to the URL given with --webhook.
+type webhookSummary struct {
+	Host     string           `json:"host"`
+	Finished time.Time        `json:"finished"`
+	Duration float64          `json:"duration"` // in seconds
+	Total    int              `json:"total"`
+	Counts   map[string]int   `json:"counts"`
+	Updated  []string         `json:"updated"`
+	Skipped  []string         `json:"skipped"`
+	Failed   []webhookFailure `json:"failed"`
+}
+
+type webhookFailure struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// validateWebhooks checks the URLs given with --webhook and --slack-webhook.
+func validateWebhooks() error {
+	for _, one := range []string{webhookURL, slackWebhookURL} {
+		if one == "" {
+			continue
+		}
+		u, err := url.Parse(one)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errs.Newf("invalid webhook URL %q; must be an http or https URL", one)
+		}
+	}
+	return nil
+}
+
+// postWebhooks posts the summary of the run to the URLs given with --webhook and --slack-webhook. Problems are
+// reported, but don't affect the outcome of the run.
+func postWebhooks(repos []*repo, root string, elapsed time.Duration) {
+	if webhookURL == "" && slackWebhookURL == "" {
+		return
+	}
+	summary := summarizeForWebhook(repos, root, elapsed)
+	if webhookURL != "" {
+		if err := postJSON(webhookURL, summary); err != nil {
+			fmt.Fprintln(os.Stderr, multirepo.ErrorText(err))
+		}
+	}
+	if slackWebhookURL != "" {
+		if err := postJSON(slackWebhookURL, map[string]string{"text": slackText(summary)}); err != nil {
+			fmt.Fprintln(os.Stderr, multirepo.ErrorText(err))
+		}
+	}
+}
+
+func summarizeForWebhook(repos []*repo, root string, elapsed time.Duration) *webhookSummary {
+	summary := &webhookSummary{
+		Finished: time.Now(),
+		Duration: elapsed.Seconds(),
+		Total:    len(repos),
+		Counts:   make(map[string]int),
+		Updated:  []string{},
+		Skipped:  []string{},
+		Failed:   []webhookFailure{},
+	}
+	if host, err := os.Hostname(); err == nil {
+		summary.Host = host
+	}
+	for _, r := range repos {
+		summary.Counts[r.result.Outcome.String()]++
+		name := displayName(root, r.path)
+		switch r.result.Outcome {
+		case multirepo.Updated:
+			summary.Updated = append(summary.Updated, name)
+		case multirepo.Skipped:
+			summary.Skipped = append(summary.Skipped, name)
+		case multirepo.Failed, multirepo.Aborted, multirepo.TimedOut:
+			summary.Failed = append(summary.Failed, webhookFailure{
+				Name:   name,
+				Status: r.result.Status,
+				Error:  r.result.Error,
+			})
+		default:
+		}
+	}
+	return summary
+}
+
+// slackText returns the summary formatted as the text of a Slack message.
+func slackText(summary *webhookSummary) string {
+	var b strings.Builder
+	elapsed := time.Duration(summary.Duration * float64(time.Second)).Round(100 * time.Millisecond)
+	fmt.Fprintf(&b, "gp on %s processed %d %s in %s", summary.Host, summary.Total,
+		plural(summary.Total, "repo", "repos"), elapsed)
+	parts := make([]string, 0, len(summary.Counts))
+	for _, o := range summaryOutcomes {
+		if n := summary.Counts[o.String()]; n != 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, o))
+		}
+	}
+	if len(parts) != 0 {
+		b.WriteString(": " + strings.Join(parts, ", "))
+	}
+	if len(summary.Updated) != 0 {
+		b.WriteString("\n*Updated:* " + strings.Join(summary.Updated, ", "))
+	}
+	if len(summary.Skipped) != 0 {
+		b.WriteString("\n*Skipped:* " + strings.Join(summary.Skipped, ", "))
+	}
+	for _, f := range summary.Failed {
+		fmt.Fprintf(&b, "\n:x: *%s*: %s", f.Name, f.Status)
+	}
+	return b.String()
+}
+
+// postJSON posts value, encoded as JSON, to target.
+func postJSON(target string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data)); err != nil {
+		return errs.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var rsp *http.Response
+	if rsp, err = http.DefaultClient.Do(req); err != nil {
+		// The error would otherwise include the URL, which for hooks such as Slack's holds the secret
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return errs.NewWithCause("unable to post to webhook "+redactURL(target), err)
+	}
+	_, err = io.Copy(io.Discard, rsp.Body)
+	xio.CloseIgnoringErrors(rsp.Body)
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return errs.Newf("webhook %s: %s", redactURL(target), rsp.Status)
+	}
+	return errs.Wrap(err)
+}
+
+// redactURL returns target without its path and query, which for hooks such as Slack's hold the secret.
+func redactURL(target string) string {
+	if u, err := url.Parse(target); err == nil {
+		return u.Scheme + "://" + u.Host + "/…"
+	}
+	return "…"
+}